@@ -0,0 +1,203 @@
+package surfrad
+
+import (
+	"math"
+	"time"
+)
+
+// StefanBoltzmann is the Stefan-Boltzmann constant in W m^-2 K^-4.
+const StefanBoltzmann = 5.670374419e-8
+
+// RadiationBudgetOptions configures the surface radiation budget analysis.
+type RadiationBudgetOptions struct {
+	// MaxZenith is the albedo cutoff in degrees; with the sun lower than this the albedo is MissingValue,
+	// as cosine response errors and shadows dominate the ratio.
+	MaxZenith float64
+	// SurfaceEmissivity is the longwave emissivity of the ground, used to remove reflected sky radiation
+	// from UpwellingIR before inverting for the skin temperature.
+	SurfaceEmissivity float64
+	// Tolerance in W m^-2 for the reported net components to count as consistent.
+	Tolerance float64
+}
+
+func DefaultRadiationBudgetOptions() RadiationBudgetOptions {
+	return RadiationBudgetOptions{MaxZenith: 75, SurfaceEmissivity: 0.98, Tolerance: 1}
+}
+
+func (o RadiationBudgetOptions) withDefaults() RadiationBudgetOptions {
+	def := DefaultRadiationBudgetOptions()
+	if o.MaxZenith <= 0 || o.MaxZenith > 90 {
+		o.MaxZenith = def.MaxZenith
+	}
+	if o.SurfaceEmissivity <= 0 || o.SurfaceEmissivity > 1 {
+		o.SurfaceEmissivity = def.SurfaceEmissivity
+	}
+	if o.Tolerance <= 0 {
+		o.Tolerance = def.Tolerance
+	}
+	return o
+}
+
+// RadiationBudget is the surface radiation budget derived from a record. Values that cannot be
+// computed are MissingValue.
+type RadiationBudget struct {
+	Timestamp time.Time `json:"timestamp"`
+
+	Albedo          float64 `json:"albedo"`
+	SkinTemperature float64 `json:"skin_temp"` // °C
+	SkyTemperature  float64 `json:"sky_temp"`  // °C, effective radiating temperature of the sky
+	SkyEmissivity   float64 `json:"sky_emissivity"`
+
+	// Residuals are the reported net components minus those recomputed from their inputs.
+	NetSolarResidual float64 `json:"netsolar_residual"`
+	NetIRResidual    float64 `json:"netir_residual"`
+	TotalNetResidual float64 `json:"totalnet_residual"`
+
+	// Consistent is false if any residual that could be computed exceeds the tolerance.
+	Consistent bool `json:"consistent"`
+}
+
+// SkinTemperature returns the surface temperature in °C from upwelling longwave, given the surface
+// emissivity and the downwelling longwave it reflects. Pass MissingValue for downwelling to treat the
+// surface as a black body.
+func SkinTemperature(upwelling, downwelling, emissivity float64) float64 {
+	emitted := upwelling
+	if downwelling != MissingValue && emissivity < 1 {
+		emitted -= (1 - emissivity) * downwelling
+	} else {
+		emissivity = 1
+	}
+	if emitted <= 0 {
+		return MissingValue
+	}
+	return math.Pow(emitted/(emissivity*StefanBoltzmann), 0.25) - celsiusToKelvin
+}
+
+// SkyTemperature returns the effective sky temperature in °C, the black body temperature that would
+// emit the downwelling longwave.
+func SkyTemperature(downwelling float64) float64 {
+	if downwelling <= 0 {
+		return MissingValue
+	}
+	return math.Pow(downwelling/StefanBoltzmann, 0.25) - celsiusToKelvin
+}
+
+// netSolarResidual checks NetSolar against both the measured global and the sum of the direct and
+// diffuse components, as SURFRAD uses the latter when it is available, and returns the smaller residual.
+// A zero NetSolar matches any expected value below tolerance, as SURFRAD reports zero rather than the
+// small or negative net solar of night and twilight.
+func (d Data) netSolarResidual(zenith, tolerance float64) float64 {
+	if IsMissing(d.NetSolar, d.QC.NetSolar) || IsMissing(d.UpwellingSolar, d.QC.UpwellingSolar) {
+		return MissingValue
+	}
+
+	var candidates []float64
+	if !IsMissing(d.DownwellingSolar, d.QC.DownwellingSolar) {
+		candidates = append(candidates, d.DownwellingSolar-d.UpwellingSolar)
+	}
+	if !IsMissing(d.DirectNormalSolar, d.QC.DirectNormalSolar) &&
+		!IsMissing(d.DownwellingDiffuseSolar, d.QC.DownwellingDiffuseSolar) {
+		sum := d.DirectNormalSolar*math.Cos(zenith*degToRad) + d.DownwellingDiffuseSolar
+		candidates = append(candidates, sum-d.UpwellingSolar)
+	}
+	if len(candidates) == 0 {
+		return MissingValue
+	}
+
+	residual := math.Inf(1)
+	for _, c := range candidates {
+		if d.NetSolar == 0 && c < tolerance {
+			c = 0
+		}
+		if r := d.NetSolar - c; math.Abs(r) < math.Abs(residual) {
+			residual = r
+		}
+	}
+	return residual
+}
+
+// RadiationBudget derives albedo, skin and sky temperatures and checks the reported net radiation
+// components for the record.
+func (d Data) RadiationBudget(loc Location, opts RadiationBudgetOptions) RadiationBudget {
+	opts = opts.withDefaults()
+
+	b := RadiationBudget{
+		Timestamp:        d.Timestamp,
+		Albedo:           MissingValue,
+		SkinTemperature:  MissingValue,
+		SkyTemperature:   MissingValue,
+		SkyEmissivity:    MissingValue,
+		NetSolarResidual: MissingValue,
+		NetIRResidual:    MissingValue,
+		TotalNetResidual: MissingValue,
+		Consistent:       true,
+	}
+
+	zen := d.Zenith(loc)
+	if zen < opts.MaxZenith {
+		b.Albedo = d.Albedo()
+	}
+
+	dwMissing := IsMissing(d.DownwellingIR, d.QC.DownwellingIR)
+	uwMissing := IsMissing(d.UpwellingIR, d.QC.UpwellingIR)
+
+	if !dwMissing {
+		b.SkyTemperature = SkyTemperature(d.DownwellingIR)
+		if !d.temperatureMissing() {
+			b.SkyEmissivity = d.DownwellingIR / (StefanBoltzmann * math.Pow(d.TemperatureC+celsiusToKelvin, 4))
+		}
+	}
+	if !uwMissing {
+		dw := MissingValue
+		if !dwMissing {
+			dw = d.DownwellingIR
+		}
+		b.SkinTemperature = SkinTemperature(d.UpwellingIR, dw, opts.SurfaceEmissivity)
+	}
+
+	b.NetSolarResidual = d.netSolarResidual(zen, opts.Tolerance)
+	if !dwMissing && !uwMissing && !IsMissing(d.NetIR, d.QC.NetIR) {
+		b.NetIRResidual = d.NetIR - (d.DownwellingIR - d.UpwellingIR)
+	}
+	if !IsMissing(d.TotalNetRadiation, d.QC.TotalNetRadiation) &&
+		!IsMissing(d.NetSolar, d.QC.NetSolar) && !IsMissing(d.NetIR, d.QC.NetIR) {
+		b.TotalNetResidual = d.TotalNetRadiation - (d.NetSolar + d.NetIR)
+	}
+
+	for _, r := range []float64{b.NetSolarResidual, b.NetIRResidual, b.TotalNetResidual} {
+		if r != MissingValue && math.Abs(r) > opts.Tolerance {
+			b.Consistent = false
+		}
+	}
+
+	return b
+}
+
+// RadiationBudget computes the radiation budget for every entry.
+func (s Station) RadiationBudget(opts RadiationBudgetOptions) []RadiationBudget {
+	out := make([]RadiationBudget, len(s.Entries))
+	for i, d := range s.Entries {
+		out[i] = d.RadiationBudget(s.LocatedAt, opts)
+	}
+	return out
+}
+
+// BroadbandAlbedo returns the albedo over all entries with the sun above the zenith cutoff, as the
+// ratio of summed upwelling to summed downwelling solar, which weights each record by its irradiance.
+// It returns MissingValue if no entry qualifies.
+func (s Station) BroadbandAlbedo(opts RadiationBudgetOptions) float64 {
+	opts = opts.withDefaults()
+
+	var up, down float64
+	for _, d := range s.Entries {
+		if d.Zenith(s.LocatedAt) >= opts.MaxZenith || d.Albedo() == MissingValue {
+			continue
+		}
+		up += d.UpwellingSolar
+		down += d.DownwellingSolar
+	}
+	if down == 0 {
+		return MissingValue
+	}
+	return up / down
+}
@@ -0,0 +1,122 @@
+package surfrad
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSkinAndSkyTemperature(t *testing.T) {
+	// a 20 °C black body emits 418.8 W m^-2
+	blackBody := StefanBoltzmann * math.Pow(293.15, 4)
+	if got := SkinTemperature(blackBody, MissingValue, 0.98); math.Abs(got-20) > 1e-9 {
+		t.Errorf("SkinTemperature() == %v, expected 20", got)
+	}
+	if got := SkyTemperature(blackBody); math.Abs(got-20) > 1e-9 {
+		t.Errorf("SkyTemperature() == %v, expected 20", got)
+	}
+
+	// reflected sky radiation is removed before inverting
+	grey := 0.95*blackBody + 0.05*300
+	if got := SkinTemperature(grey, 300, 0.95); math.Abs(got-20) > 1e-9 {
+		t.Errorf("SkinTemperature() with reflection == %v, expected 20", got)
+	}
+
+	if SkinTemperature(0, MissingValue, 1) != MissingValue || SkyTemperature(-1) != MissingValue {
+		t.Error("expected MissingValue for non-positive irradiance")
+	}
+}
+
+func TestDataRadiationBudget(t *testing.T) {
+	loc := desertRock
+	opts := DefaultRadiationBudgetOptions()
+
+	good := Data{
+		SolarZenithAngle:        60,
+		DownwellingSolar:        500,
+		UpwellingSolar:          100,
+		DirectNormalSolar:       700,
+		DownwellingDiffuseSolar: 150,
+		DownwellingIR:           300,
+		UpwellingIR:             400,
+		NetSolar:                400,
+		NetIR:                   -100,
+		TotalNetRadiation:       300,
+		TemperatureC:            15,
+	}
+
+	b := good.RadiationBudget(loc, opts)
+	if !b.Consistent || b.NetSolarResidual != 0 || b.NetIRResidual != 0 || b.TotalNetResidual != 0 {
+		t.Errorf("expected a consistent record: %+v", b)
+	}
+	if math.Abs(b.Albedo-0.2) > 1e-9 {
+		t.Errorf("albedo == %v, expected 0.2", b.Albedo)
+	}
+	if b.SkyEmissivity <= 0.7 || b.SkyEmissivity >= 0.9 {
+		t.Errorf("sky emissivity == %v", b.SkyEmissivity)
+	}
+	if b.SkyTemperature >= b.SkinTemperature {
+		t.Errorf("sky (%v) should be colder than the surface (%v)", b.SkyTemperature, b.SkinTemperature)
+	}
+
+	// net solar computed from the direct and diffuse components is accepted too
+	sum := good
+	sum.NetSolar = 700*0.5 + 150 - 100
+	sum.TotalNetRadiation = sum.NetSolar + sum.NetIR
+	if b = sum.RadiationBudget(loc, opts); !b.Consistent {
+		t.Errorf("component sum net solar should be consistent: %+v", b)
+	}
+
+	// a zero net solar stands in for small and negative values at night, but not for daylight
+	night := Data{SolarZenithAngle: 100, DownwellingSolar: -2, UpwellingSolar: 0.5}
+	if b = night.RadiationBudget(loc, opts); b.NetSolarResidual != 0 {
+		t.Errorf("zero net solar at night should match: %+v", b)
+	}
+	night.NetSolar = -2.5
+	if b = night.RadiationBudget(loc, opts); b.NetSolarResidual != 0 {
+		t.Errorf("reported negative net solar should match as is: %+v", b)
+	}
+	day := good
+	day.NetSolar = 0
+	if b = day.RadiationBudget(loc, opts); b.Consistent || math.Abs(b.NetSolarResidual+400) > 1e-9 {
+		t.Errorf("zero net solar in daylight should be off by 400: %+v", b)
+	}
+
+	bad := good
+	bad.NetIR = -80
+	if b = bad.RadiationBudget(loc, opts); b.Consistent || math.Abs(b.NetIRResidual-20) > 1e-9 {
+		t.Errorf("expected a net IR inconsistency of 20: %+v", b)
+	}
+
+	low := good
+	low.SolarZenithAngle = 80
+	if b = low.RadiationBudget(loc, opts); b.Albedo != MissingValue {
+		t.Errorf("albedo beyond the zenith cutoff == %v", b.Albedo)
+	}
+
+	missing := good
+	missing.UpwellingIR, missing.QC.UpwellingIR = 0, QCMissing
+	if b = missing.RadiationBudget(loc, opts); b.SkinTemperature != MissingValue || b.NetIRResidual != MissingValue || !b.Consistent {
+		t.Errorf("missing upwelling IR should not be checked: %+v", b)
+	}
+}
+
+func TestStationRadiationBudget(t *testing.T) {
+	station := readTestStation(t)
+
+	budget := station.RadiationBudget(DefaultRadiationBudgetOptions())
+	if len(budget) != station.Len() {
+		t.Fatalf("expected %d results, got %d", station.Len(), len(budget))
+	}
+	for _, b := range budget {
+		if !b.Consistent {
+			t.Errorf("%s: reported net radiation inconsistent: %+v", b.Timestamp, b)
+		}
+		if b.SkinTemperature != MissingValue && (b.SkinTemperature < -20 || b.SkinTemperature > 60) {
+			t.Errorf("%s: implausible skin temperature %v", b.Timestamp, b.SkinTemperature)
+		}
+	}
+
+	if albedo := station.BroadbandAlbedo(DefaultRadiationBudgetOptions()); albedo < 0.1 || albedo > 0.4 {
+		t.Errorf("desert albedo == %v", albedo)
+	}
+}
@@ -0,0 +1,248 @@
+package surfrad
+
+import (
+	"math"
+	"time"
+)
+
+// Zenith angles in degrees that define sunrise, sunset and the twilights. Sunrise and sunset include
+// refraction and the radius of the solar disc.
+const (
+	SunriseZenith      = 90.833
+	CivilZenith        = 96.0
+	NauticalZenith     = 102.0
+	AstronomicalZenith = 108.0
+)
+
+// SunEvents are the solar events of one local day. Events that don't happen on that day, as in polar
+// day or night, are the zero time.
+type SunEvents struct {
+	Date time.Time `json:"date"` // local midnight
+
+	AstronomicalDawn time.Time `json:"astronomical_dawn"`
+	NauticalDawn     time.Time `json:"nautical_dawn"`
+	CivilDawn        time.Time `json:"civil_dawn"`
+	Sunrise          time.Time `json:"sunrise"`
+	SolarNoon        time.Time `json:"solar_noon"`
+	Sunset           time.Time `json:"sunset"`
+	CivilDusk        time.Time `json:"civil_dusk"`
+	NauticalDusk     time.Time `json:"nautical_dusk"`
+	AstronomicalDusk time.Time `json:"astronomical_dusk"`
+
+	DayLength time.Duration `json:"day_length"` // sunrise to sunset; 24h during polar day
+}
+
+// In returns the events with every time expressed in tz.
+func (e SunEvents) In(tz *time.Location) SunEvents {
+	for _, t := range []*time.Time{
+		&e.Date, &e.AstronomicalDawn, &e.NauticalDawn, &e.CivilDawn, &e.Sunrise, &e.SolarNoon,
+		&e.Sunset, &e.CivilDusk, &e.NauticalDusk, &e.AstronomicalDusk,
+	} {
+		if !t.IsZero() {
+			*t = t.In(tz)
+		}
+	}
+	return e
+}
+
+// UTC returns the events with every time expressed in UTC.
+func (e SunEvents) UTC() SunEvents {
+	return e.In(time.UTC)
+}
+
+func minutesDuration(m float64) time.Duration {
+	return time.Duration(m * float64(time.Minute))
+}
+
+// solarNoon refines a guess into the time of the sun's transit, when the hour angle is zero.
+func (l Location) solarNoon(guess time.Time) time.Time {
+	t := guess
+	for i := 0; i < 3; i++ {
+		// the hour angle advances 15 degrees an hour, 4 minutes per degree
+		t = t.Add(-minutesDuration(4 * l.SunPosition(t).HourAngle))
+	}
+	return t.Round(time.Second)
+}
+
+// eventHourAngle returns the hour angle in degrees at which the sun reaches zenith, or NaN with
+// below reporting which side of zenith it stays on all day.
+func (l Location) eventHourAngle(t time.Time, zenith float64) (ha float64, below bool) {
+	decl := l.SunPosition(t).Declination * degToRad
+	lat := l.Latitude * degToRad
+	cosH := (math.Cos(zenith*degToRad) - math.Sin(lat)*math.Sin(decl)) / (math.Cos(lat) * math.Cos(decl))
+	switch {
+	case cosH > 1:
+		return math.NaN(), true
+	case cosH < -1:
+		return math.NaN(), false
+	}
+	return math.Acos(cosH) * radToDeg, false
+}
+
+// crossing finds when the sun crosses zenith before (rising) or after the transit at noon, or the
+// zero time if it doesn't that day.
+func (l Location) crossing(noon time.Time, zenith float64, rising bool) time.Time {
+	sign := 1.0
+	if rising {
+		sign = -1
+	}
+
+	t := noon
+	for i := 0; i < 4; i++ {
+		ha, _ := l.eventHourAngle(t, zenith)
+		if math.IsNaN(ha) {
+			return time.Time{}
+		}
+		// move so that the current hour angle becomes the target one
+		t = t.Add(minutesDuration(4 * (sign*ha - l.SunPosition(t).HourAngle)))
+	}
+	return t.Round(time.Second)
+}
+
+// SunEvents computes the solar events for the local day containing day in tz.
+func (l Location) SunEvents(day time.Time, tz *time.Location) SunEvents {
+	midnight := LocalDay(day, tz)
+
+	noon := l.solarNoon(midnight.Add(12 * time.Hour))
+	e := SunEvents{
+		Date:             midnight,
+		AstronomicalDawn: l.crossing(noon, AstronomicalZenith, true),
+		NauticalDawn:     l.crossing(noon, NauticalZenith, true),
+		CivilDawn:        l.crossing(noon, CivilZenith, true),
+		Sunrise:          l.crossing(noon, SunriseZenith, true),
+		SolarNoon:        noon,
+		Sunset:           l.crossing(noon, SunriseZenith, false),
+		CivilDusk:        l.crossing(noon, CivilZenith, false),
+		NauticalDusk:     l.crossing(noon, NauticalZenith, false),
+		AstronomicalDusk: l.crossing(noon, AstronomicalZenith, false),
+	}
+
+	switch {
+	case !e.Sunrise.IsZero() && !e.Sunset.IsZero():
+		e.DayLength = e.Sunset.Sub(e.Sunrise)
+	default:
+		if _, below := l.eventHourAngle(noon, SunriseZenith); !below {
+			e.DayLength = 24 * time.Hour
+		}
+	}
+
+	return e.In(tz)
+}
+
+// SunCalendar returns the solar events for every local day in tz from start through end.
+func (l Location) SunCalendar(start, end time.Time, tz *time.Location) []SunEvents {
+	end = end.In(tz)
+	day := LocalDay(start, tz)
+
+	var out []SunEvents
+	for !day.After(end) {
+		out = append(out, l.SunEvents(day, tz))
+		day = day.AddDate(0, 0, 1)
+	}
+	return out
+}
+
+// SunCalendar returns the solar events in the station's local standard time for every local day
+// covered by its entries, which are assumed to be sorted.
+func (s Station) SunCalendar() []SunEvents {
+	if len(s.Entries) == 0 {
+		return nil
+	}
+	return s.LocatedAt.SunCalendar(s.Entries[0].Timestamp, s.Entries[len(s.Entries)-1].Timestamp, s.TimeZone())
+}
+
+type DayPhase int
+
+const (
+	Night DayPhase = iota
+	Twilight
+	Daylight
+)
+
+func (p DayPhase) String() string {
+	switch p {
+	case Night:
+		return "night"
+	case Twilight:
+		return "twilight"
+	case Daylight:
+		return "day"
+	default:
+		return "unknown"
+	}
+}
+
+// Phase classifies t against the day's events. Twilight lasts until the sun is lower than twilightZenith,
+// one of CivilZenith, NauticalZenith or AstronomicalZenith.
+func (e SunEvents) Phase(t time.Time, twilightZenith float64) DayPhase {
+	between := func(from, to time.Time) bool {
+		return !from.IsZero() && !to.IsZero() && !t.Before(from) && t.Before(to)
+	}
+
+	if between(e.Sunrise, e.Sunset) || (e.Sunrise.IsZero() && e.DayLength == 24*time.Hour) {
+		return Daylight
+	}
+
+	var dawn, dusk time.Time
+	switch twilightZenith {
+	case NauticalZenith:
+		dawn, dusk = e.NauticalDawn, e.NauticalDusk
+	case AstronomicalZenith:
+		dawn, dusk = e.AstronomicalDawn, e.AstronomicalDusk
+	default:
+		dawn, dusk = e.CivilDawn, e.CivilDusk
+	}
+
+	if dawn.IsZero() && !e.Sunrise.IsZero() {
+		// the sun never gets low enough for night
+		return Twilight
+	}
+	if between(dawn, dusk) {
+		return Twilight
+	}
+	return Night
+}
+
+// Segment is a run of consecutive entries in the same phase of the day.
+type Segment struct {
+	Phase DayPhase `json:"phase"`
+	Period
+	Entries []Data `json:"entries"`
+}
+
+// Segments splits the entries into runs of night, twilight and daylight, using the solar events of each
+// local day and twilightZenith as in SunEvents.Phase. Entries are classified at the middle of their
+// averaging minute, and share the backing array of s.Entries.
+func (s Station) Segments(twilightZenith float64) []Segment {
+	tz := s.TimeZone()
+	events := make(map[time.Time]SunEvents)
+
+	var (
+		segments []Segment
+		start    int
+	)
+	for i, d := range s.Entries {
+		t := d.Timestamp.Add(-30 * time.Second).In(tz)
+		day := LocalDay(t, tz)
+		e, ok := events[day]
+		if !ok {
+			e = s.LocatedAt.SunEvents(day, tz)
+			events[day] = e
+		}
+
+		phase := e.Phase(t, twilightZenith)
+		if n := len(segments); n > 0 && segments[n-1].Phase == phase {
+			segments[n-1].End = d.Timestamp
+			segments[n-1].Entries = s.Entries[start : i+1]
+			continue
+		}
+		start = i
+		segments = append(segments, Segment{
+			Phase:   phase,
+			Period:  Period{Start: d.Timestamp, End: d.Timestamp},
+			Entries: s.Entries[i : i+1],
+		})
+	}
+
+	return segments
+}
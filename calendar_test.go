@@ -0,0 +1,134 @@
+package surfrad
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSunEventsMatchZenithColumn(t *testing.T) {
+	station := readTestStation(t)
+
+	cal := station.SunCalendar()
+	if len(cal) != 2 {
+		// a UTC day spans two PST days
+		t.Fatalf("expected 2 local days, got %d", len(cal))
+	}
+	e := cal[0]
+	if e.Date.Location() != station.TimeZone() || e.Date.Day() != 16 {
+		t.Errorf("unexpected local day %v", e.Date)
+	}
+
+	// the zenith column is for the middle of the minute ending at the timestamp
+	var (
+		sunset, noon time.Time
+		minZen       = math.Inf(1)
+	)
+	for i := 1; i < station.Len(); i++ {
+		prev, cur := station.Entries[i-1], station.Entries[i]
+		if prev.SolarZenithAngle < SunriseZenith && cur.SolarZenithAngle >= SunriseZenith {
+			sunset = cur.Timestamp.Add(-30 * time.Second)
+		}
+		minZen = math.Min(minZen, cur.SolarZenithAngle)
+	}
+	// the zenith barely changes around noon, so take the middle of the minimum's span
+	var first, last time.Time
+	for _, d := range station.Entries {
+		if d.SolarZenithAngle <= minZen+0.01 {
+			if first.IsZero() {
+				first = d.Timestamp
+			}
+			last = d.Timestamp
+		}
+	}
+	noon = first.Add(last.Sub(first)/2 - 30*time.Second)
+
+	// Feb 16 sunset local is at 01:25 UTC on the 17th
+	if d := e.Sunset.Sub(sunset); d < -time.Minute || d > time.Minute {
+		t.Errorf("sunset %v, zenith column crosses at %v", e.Sunset.UTC(), sunset)
+	}
+	if d := cal[1].SolarNoon.Sub(noon); d < -time.Minute || d > time.Minute {
+		t.Errorf("solar noon %v, zenith column minimum at %v", cal[1].SolarNoon.UTC(), noon)
+	}
+
+	sunrise := cal[1].Sunrise.In(station.TimeZone())
+	if sunrise.Hour() != 6 || sunrise.Minute() < 25 || sunrise.Minute() > 35 {
+		t.Errorf("unexpected local sunrise %v", sunrise)
+	}
+
+	if !(e.AstronomicalDawn.Before(e.NauticalDawn) && e.NauticalDawn.Before(e.CivilDawn) &&
+		e.CivilDawn.Before(e.Sunrise) && e.Sunrise.Before(e.SolarNoon) && e.SolarNoon.Before(e.Sunset) &&
+		e.Sunset.Before(e.CivilDusk) && e.CivilDusk.Before(e.NauticalDusk) && e.NauticalDusk.Before(e.AstronomicalDusk)) {
+		t.Errorf("events out of order: %+v", e)
+	}
+	if e.DayLength != e.Sunset.Sub(e.Sunrise) {
+		t.Errorf("day length %v", e.DayLength)
+	}
+}
+
+func TestSunEventsPolar(t *testing.T) {
+	svalbard := Location{Latitude: 78.2, Longitude: 15.6}
+
+	summer := svalbard.SunEvents(time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC), time.UTC)
+	if !summer.Sunrise.IsZero() || summer.DayLength != 24*time.Hour {
+		t.Errorf("expected polar day: %+v", summer)
+	}
+	if summer.Phase(summer.Date.Add(time.Hour), CivilZenith) != Daylight {
+		t.Error("expected daylight at midnight in polar day")
+	}
+
+	winter := svalbard.SunEvents(time.Date(2024, 12, 21, 0, 0, 0, 0, time.UTC), time.UTC)
+	if !winter.Sunrise.IsZero() || winter.DayLength != 0 || winter.NauticalDawn.IsZero() {
+		t.Errorf("expected polar night with nautical twilight: %+v", winter)
+	}
+	if p := winter.Phase(winter.SolarNoon, CivilZenith); p != Night {
+		t.Errorf("noon in polar night with civil twilight is %v", p)
+	}
+	if p := winter.Phase(winter.SolarNoon, NauticalZenith); p != Twilight {
+		t.Errorf("noon in polar night with nautical twilight is %v", p)
+	}
+}
+
+func TestSegments(t *testing.T) {
+	station := readTestStation(t)
+
+	segments := station.Segments(CivilZenith)
+
+	var (
+		total  int
+		phases []DayPhase
+	)
+	for i, seg := range segments {
+		total += len(seg.Entries)
+		phases = append(phases, seg.Phase)
+		if !seg.Start.Equal(seg.Entries[0].Timestamp) || !seg.End.Equal(seg.Entries[len(seg.Entries)-1].Timestamp) {
+			t.Errorf("segment %d period doesn't match its entries", i)
+		}
+	}
+	if total != station.Len() {
+		t.Errorf("segments cover %d entries, expected %d", total, station.Len())
+	}
+
+	// the UTC day starts in the Nevada evening
+	expected := []DayPhase{Daylight, Twilight, Night, Twilight, Daylight}
+	if len(phases) != len(expected) {
+		t.Fatalf("phases %v, expected %v", phases, expected)
+	}
+	for i := range expected {
+		if phases[i] != expected[i] {
+			t.Errorf("phases %v, expected %v", phases, expected)
+			break
+		}
+	}
+
+	for _, d := range segments[2].Entries {
+		if d.SolarZenithAngle < CivilZenith-0.5 {
+			t.Errorf("%s: zenith %v at night", d.Timestamp, d.SolarZenithAngle)
+		}
+	}
+	for _, d := range segments[4].Entries {
+		if d.SolarZenithAngle > SunriseZenith+0.5 {
+			t.Errorf("%s: zenith %v in daylight", d.Timestamp, d.SolarZenithAngle)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package surfrad
+
+import (
+	"math"
+	"time"
+)
+
+// ClearnessOptions configures clearness index computations.
+type ClearnessOptions struct {
+	// SolarConstant in W m^-2, scaled by earth-sun distance to get extraterrestrial irradiance.
+	SolarConstant float64
+	// MaxZenith is the low-sun cutoff in degrees; records with the sun lower than this yield MissingValue,
+	// as the ratios blow up near the horizon.
+	MaxZenith float64
+	// LocalTime aligns BinnedClearness bins to the station's local standard time instead of UTC,
+	// so daily bins run from local midnight.
+	LocalTime bool
+}
+
+func DefaultClearnessOptions() ClearnessOptions {
+	return ClearnessOptions{SolarConstant: DefaultSolarConstant, MaxZenith: 85}
+}
+
+// Clearness holds the irradiance ratios for a record or an aggregated bin. Ratios that cannot be
+// computed are MissingValue.
+type Clearness struct {
+	Timestamp time.Time `json:"timestamp"`
+
+	// Extraterrestrial is the extraterrestrial irradiance on a horizontal surface (W m^-2).
+	Extraterrestrial float64 `json:"extraterrestrial_horizontal"`
+
+	// Kt is the clearness index, global horizontal / extraterrestrial horizontal.
+	Kt float64 `json:"kt"`
+	// Kb is the direct beam clearness index, direct normal / extraterrestrial normal.
+	Kb float64 `json:"kb"`
+	// Kd is the diffuse fraction, diffuse horizontal / global horizontal.
+	Kd float64 `json:"kd"`
+	// BeamFraction is the direct beam fraction, direct horizontal / global horizontal.
+	BeamFraction float64 `json:"beam_fraction"`
+	// KtPrime is the zenith independent clearness index of Perez et al. (1990).
+	KtPrime float64 `json:"kt_prime"`
+}
+
+func missingClearness(ts time.Time) Clearness {
+	return Clearness{
+		Timestamp:        ts,
+		Extraterrestrial: MissingValue,
+		Kt:               MissingValue,
+		Kb:               MissingValue,
+		Kd:               MissingValue,
+		BeamFraction:     MissingValue,
+		KtPrime:          MissingValue,
+	}
+}
+
+func (o ClearnessOptions) withDefaults() ClearnessOptions {
+	def := DefaultClearnessOptions()
+	if o.SolarConstant <= 0 {
+		o.SolarConstant = def.SolarConstant
+	}
+	if o.MaxZenith <= 0 || o.MaxZenith > 90 {
+		o.MaxZenith = def.MaxZenith
+	}
+	return o
+}
+
+// Zenith returns the record's solar zenith angle, falling back to computing it from the location
+// when the file's value is missing. SURFRAD's zenith is for the middle of the averaging minute.
+func (d Data) Zenith(loc Location) float64 {
+	if d.SolarZenithAngle != 0 && d.SolarZenithAngle != MissingValue {
+		return d.SolarZenithAngle
+	}
+	return loc.SunPosition(d.Timestamp.Add(-30 * time.Second)).ApparentZenith
+}
+
+// modifiedClearness applies the Perez et al. (1990) airmass normalization to kt.
+func modifiedClearness(kt, zenith float64) float64 {
+	am := RelativeAirmass(zenith)
+	if kt == MissingValue || am == MissingValue {
+		return MissingValue
+	}
+	return kt / (1.031*math.Exp(-1.4/(0.9+9.4/am)) + 0.1)
+}
+
+func ratio(num, den float64, numMissing bool) float64 {
+	if numMissing || den <= 0 {
+		return MissingValue
+	}
+	return num / den
+}
+
+// Clearness computes the irradiance ratios for the record at the given location.
+func (d Data) Clearness(loc Location, opts ClearnessOptions) Clearness {
+	opts = opts.withDefaults()
+
+	zen := d.Zenith(loc)
+	if zen > opts.MaxZenith {
+		return missingClearness(d.Timestamp)
+	}
+
+	cosZ := math.Cos(zen * degToRad)
+	e0 := ExtraterrestrialIrradiance(d.Timestamp, opts.SolarConstant)
+
+	ghiMissing := IsMissing(d.DownwellingSolar, d.QC.DownwellingSolar)
+	dniMissing := IsMissing(d.DirectNormalSolar, d.QC.DirectNormalSolar)
+	dhiMissing := IsMissing(d.DownwellingDiffuseSolar, d.QC.DownwellingDiffuseSolar)
+
+	c := Clearness{
+		Timestamp:        d.Timestamp,
+		Extraterrestrial: e0 * cosZ,
+		Kt:               ratio(d.DownwellingSolar, e0*cosZ, ghiMissing),
+		Kb:               ratio(d.DirectNormalSolar, e0, dniMissing),
+		Kd:               MissingValue,
+		BeamFraction:     MissingValue,
+	}
+
+	if !ghiMissing && d.DownwellingSolar > 0 {
+		c.Kd = ratio(d.DownwellingDiffuseSolar, d.DownwellingSolar, dhiMissing)
+		c.BeamFraction = ratio(d.DirectNormalSolar*cosZ, d.DownwellingSolar, dniMissing)
+	}
+
+	c.KtPrime = modifiedClearness(c.Kt, zen)
+
+	return c
+}
+
+// Clearness computes the irradiance ratios for every entry.
+func (s Station) Clearness(opts ClearnessOptions) []Clearness {
+	out := make([]Clearness, len(s.Entries))
+	for i, d := range s.Entries {
+		out[i] = d.Clearness(s.LocatedAt, opts)
+	}
+	return out
+}
+
+// BinnedClearness aggregates entries into fixed width bins, aligned to UTC unless opts.LocalTime is set,
+// and computes irradiance weighted ratios for each bin (e.g. hourly kt is the hour's total GHI over its
+// total extraterrestrial irradiance).
+// Only entries above the zenith cutoff with the inputs for a given ratio contribute to it.
+func (s Station) BinnedClearness(width time.Duration, opts ClearnessOptions) []Clearness {
+	opts = opts.withDefaults()
+
+	tz := time.UTC
+	if opts.LocalTime {
+		tz = s.TimeZone()
+	}
+
+	type sums struct {
+		start                time.Time
+		ghi, e0h, zenith     float64
+		dni, e0n             float64
+		dhi, ghiD            float64
+		beam, ghiB           float64
+		nKt, nKb, nKd, nBeam int
+	}
+
+	var (
+		out []Clearness
+		cur *sums
+	)
+
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		c := missingClearness(cur.start)
+		if cur.nKt > 0 {
+			c.Extraterrestrial = cur.e0h / float64(cur.nKt)
+			c.Kt = ratio(cur.ghi, cur.e0h, false)
+			c.KtPrime = modifiedClearness(c.Kt, cur.zenith/float64(cur.nKt))
+		}
+		if cur.nKb > 0 {
+			c.Kb = ratio(cur.dni, cur.e0n, false)
+		}
+		if cur.nKd > 0 {
+			c.Kd = ratio(cur.dhi, cur.ghiD, false)
+		}
+		if cur.nBeam > 0 {
+			c.BeamFraction = ratio(cur.beam, cur.ghiB, false)
+		}
+		out = append(out, c)
+	}
+
+	for _, d := range s.Entries {
+		bin := TruncateIn(d.Timestamp, width, tz)
+		if cur == nil || !bin.Equal(cur.start) {
+			flush()
+			cur = &sums{start: bin}
+		}
+
+		zen := d.Zenith(s.LocatedAt)
+		if zen > opts.MaxZenith {
+			continue
+		}
+
+		cosZ := math.Cos(zen * degToRad)
+		e0 := ExtraterrestrialIrradiance(d.Timestamp, opts.SolarConstant)
+
+		ghiOK := !IsMissing(d.DownwellingSolar, d.QC.DownwellingSolar)
+		dniOK := !IsMissing(d.DirectNormalSolar, d.QC.DirectNormalSolar)
+		dhiOK := !IsMissing(d.DownwellingDiffuseSolar, d.QC.DownwellingDiffuseSolar)
+
+		if ghiOK {
+			cur.ghi += d.DownwellingSolar
+			cur.e0h += e0 * cosZ
+			cur.nKt++
+			cur.zenith += zen
+		}
+		if dniOK {
+			cur.dni += d.DirectNormalSolar
+			cur.e0n += e0
+			cur.nKb++
+		}
+		if ghiOK && dhiOK {
+			cur.dhi += d.DownwellingDiffuseSolar
+			cur.ghiD += d.DownwellingSolar
+			cur.nKd++
+		}
+		if ghiOK && dniOK {
+			cur.beam += d.DirectNormalSolar * cosZ
+			cur.ghiB += d.DownwellingSolar
+			cur.nBeam++
+		}
+	}
+	flush()
+
+	return out
+}
@@ -0,0 +1,98 @@
+package surfrad
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDataClearness(t *testing.T) {
+	loc := Location{Latitude: 36.62, Longitude: -116.02, Elevation: 1007}
+	ts := time.Date(2024, 2, 17, 20, 0, 0, 0, time.UTC)
+	e0 := ExtraterrestrialIrradiance(ts, DefaultSolarConstant)
+	cosZ := math.Cos(48.55 * degToRad)
+
+	d := Data{
+		Timestamp:               ts,
+		SolarZenithAngle:        48.55,
+		DownwellingSolar:        700,
+		DirectNormalSolar:       950,
+		DownwellingDiffuseSolar: 70,
+	}
+
+	c := d.Clearness(loc, DefaultClearnessOptions())
+
+	cases := []struct {
+		name     string
+		got      float64
+		expected float64
+	}{
+		{"kt", c.Kt, 700 / (e0 * cosZ)},
+		{"kb", c.Kb, 950 / e0},
+		{"kd", c.Kd, 0.1},
+		{"beam fraction", c.BeamFraction, 950 * cosZ / 700},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if math.Abs(tc.got-tc.expected) > 1e-9 {
+				t.Errorf("got %v, expected %v", tc.got, tc.expected)
+			}
+		})
+	}
+
+	if c.KtPrime <= c.Kt || c.KtPrime > 1.2 {
+		t.Errorf("kt' %v should exceed kt %v at airmass 1.5", c.KtPrime, c.Kt)
+	}
+
+	// custom solar constant scales kt inversely
+	c2 := d.Clearness(loc, ClearnessOptions{SolarConstant: DefaultSolarConstant / 2, MaxZenith: 85})
+	if math.Abs(c2.Kt-2*c.Kt) > 1e-9 {
+		t.Errorf("halving the solar constant should double kt: %v vs %v", c2.Kt, c.Kt)
+	}
+
+	// low sun cutoff
+	d.SolarZenithAngle = 88
+	if c = d.Clearness(loc, DefaultClearnessOptions()); c.Kt != MissingValue {
+		t.Errorf("kt at zenith 88 with cutoff 85 should be missing, got %v", c.Kt)
+	}
+	if c = d.Clearness(loc, ClearnessOptions{MaxZenith: 89}); c.Kt == MissingValue {
+		t.Error("kt at zenith 88 with cutoff 89 should be computed")
+	}
+
+	// missing inputs
+	d.SolarZenithAngle = 48.55
+	d.DirectNormalSolar = 0
+	d.QC.DirectNormalSolar = QCMissing
+	c = d.Clearness(loc, DefaultClearnessOptions())
+	if c.Kb != MissingValue || c.BeamFraction != MissingValue || c.Kt == MissingValue {
+		t.Errorf("missing DNI should only affect kb and beam fraction: %+v", c)
+	}
+}
+
+func TestStationBinnedClearness(t *testing.T) {
+	station := readTestStation(t)
+
+	perRecord := station.Clearness(DefaultClearnessOptions())
+	if len(perRecord) != station.Len() {
+		t.Fatalf("expected one result per entry")
+	}
+
+	hourly := station.BinnedClearness(time.Hour, DefaultClearnessOptions())
+	if len(hourly) != 24 {
+		t.Fatalf("expected 24 hourly bins, got %d", len(hourly))
+	}
+
+	for _, c := range hourly {
+		night := c.Timestamp.Hour() >= 2 && c.Timestamp.Hour() < 14
+		switch {
+		case night && c.Kt != MissingValue:
+			t.Errorf("%s: expected missing kt at night, got %v", c.Timestamp, c.Kt)
+		case !night && c.Kt != MissingValue && (c.Kt < 0 || c.Kt > 1.2):
+			t.Errorf("%s: implausible kt %v", c.Timestamp, c.Kt)
+		}
+	}
+
+	if noon := hourly[20]; noon.Kt == MissingValue || noon.Kd == MissingValue || noon.Kd > 1 {
+		t.Errorf("unexpected midday bin: %+v", noon)
+	}
+}
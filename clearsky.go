@@ -0,0 +1,219 @@
+package surfrad
+
+import (
+	"math"
+	"time"
+)
+
+// ClearSky is modeled cloud free irradiance in W m^-2. Components a model doesn't produce are MissingValue.
+type ClearSky struct {
+	GHI float64 `json:"ghi"`
+	DNI float64 `json:"dni"`
+	DHI float64 `json:"dhi"`
+}
+
+// ClearSkyModel computes clear-sky irradiance for a time and place.
+type ClearSkyModel interface {
+	ClearSky(t time.Time, loc Location) ClearSky
+}
+
+// pressureAtElevation returns the standard atmosphere pressure in hPa at an elevation in meters.
+func pressureAtElevation(elevation float64) float64 {
+	return 1013.25 * math.Pow(1-2.25577e-5*elevation, 5.25588)
+}
+
+// Haurwitz is the Haurwitz (1945) model, which only provides GHI.
+type Haurwitz struct{}
+
+func (Haurwitz) ClearSky(t time.Time, loc Location) ClearSky {
+	zen := loc.SunPosition(t).ApparentZenith
+	cs := ClearSky{DNI: MissingValue, DHI: MissingValue}
+	if zen < 90 {
+		cosZ := math.Cos(zen * degToRad)
+		cs.GHI = 1098 * cosZ * math.Exp(-0.059/cosZ)
+	}
+	return cs
+}
+
+// Ineichen is the Ineichen & Perez (2002) model as formulated by Ineichen (2008), driven by the
+// Linke turbidity at air mass 2 and the station elevation.
+type Ineichen struct {
+	LinkeTurbidity float64
+	SolarConstant  float64 // defaults to DefaultSolarConstant
+}
+
+func NewIneichen(linkeTurbidity float64) Ineichen {
+	return Ineichen{LinkeTurbidity: linkeTurbidity, SolarConstant: DefaultSolarConstant}
+}
+
+func (m Ineichen) ClearSky(t time.Time, loc Location) ClearSky {
+	zen := loc.SunPosition(t).ApparentZenith
+	if zen >= 90 {
+		return ClearSky{}
+	}
+
+	sc := m.SolarConstant
+	if sc <= 0 {
+		sc = DefaultSolarConstant
+	}
+
+	alt := float64(loc.Elevation)
+	tl := m.LinkeTurbidity
+	cosZ := math.Cos(zen * degToRad)
+	am := AbsoluteAirmass(RelativeAirmass(zen), pressureAtElevation(alt))
+	e0 := ExtraterrestrialIrradiance(t, sc)
+
+	fh1 := math.Exp(-alt / 8000)
+	fh2 := math.Exp(-alt / 1250)
+	cg1 := 5.09e-5*alt + 0.868
+	cg2 := 3.92e-5*alt + 0.0387
+
+	ghi := math.Max(0, cg1*e0*cosZ*math.Exp(-cg2*am*(fh1+fh2*(tl-1))))
+
+	b := 0.664 + 0.163/fh1
+	dni := b * e0 * math.Exp(-0.09*am*(tl-1))
+	// limit DNI so that it can't exceed what GHI allows
+	dniLimit := ghi * math.Max(0, (1-(0.1-0.2*math.Exp(-tl))/(0.1+0.882/fh1))/cosZ)
+	dni = math.Max(0, math.Min(dni, dniLimit))
+
+	return ClearSky{GHI: ghi, DNI: dni, DHI: math.Max(0, ghi-dni*cosZ)}
+}
+
+// Bird is the Bird & Hulstrom (1981) broadband model. Zero valued fields take typical defaults
+// from DefaultBird, except Pressure which defaults to the standard atmosphere at the station elevation,
+// and Albedo, for which zero is a valid ground and only a negative value takes the default.
+type Bird struct {
+	Pressure          float64 // hPa
+	Ozone             float64 // atm-cm
+	PrecipitableWater float64 // cm
+	AOD500            float64 // aerosol optical depth at 500 nm
+	AOD380            float64 // aerosol optical depth at 380 nm
+	Albedo            float64 // ground albedo
+	Asymmetry         float64 // aerosol forward scattering ratio
+	SolarConstant     float64
+}
+
+func DefaultBird() Bird {
+	return Bird{
+		Ozone:             0.3,
+		PrecipitableWater: 1.5,
+		AOD500:            0.1,
+		AOD380:            0.15,
+		Albedo:            0.2,
+		Asymmetry:         0.85,
+		SolarConstant:     DefaultSolarConstant,
+	}
+}
+
+func (m Bird) withDefaults(loc Location) Bird {
+	def := DefaultBird()
+	if m.Pressure <= 0 {
+		m.Pressure = pressureAtElevation(float64(loc.Elevation))
+	}
+	if m.Ozone <= 0 {
+		m.Ozone = def.Ozone
+	}
+	if m.PrecipitableWater <= 0 {
+		m.PrecipitableWater = def.PrecipitableWater
+	}
+	if m.AOD500 <= 0 {
+		m.AOD500 = def.AOD500
+	}
+	if m.AOD380 <= 0 {
+		m.AOD380 = def.AOD380
+	}
+	if m.Albedo < 0 {
+		m.Albedo = def.Albedo
+	}
+	if m.Asymmetry <= 0 {
+		m.Asymmetry = def.Asymmetry
+	}
+	if m.SolarConstant <= 0 {
+		m.SolarConstant = def.SolarConstant
+	}
+	return m
+}
+
+func (m Bird) ClearSky(t time.Time, loc Location) ClearSky {
+	zen := loc.SunPosition(t).ApparentZenith
+	if zen >= 90 {
+		return ClearSky{}
+	}
+
+	m = m.withDefaults(loc)
+
+	cosZ := math.Cos(zen * degToRad)
+	am := RelativeAirmass(zen)
+	amP := AbsoluteAirmass(am, m.Pressure)
+	e0 := ExtraterrestrialIrradiance(t, m.SolarConstant)
+
+	tRayleigh := math.Exp(-0.0903 * math.Pow(amP, 0.84) * (1 + amP - math.Pow(amP, 1.01)))
+
+	amO3 := m.Ozone * am
+	tOzone := 1 - 0.1611*amO3*math.Pow(1+139.48*amO3, -0.3034) - 0.002715*amO3/(1+0.044*amO3+0.0003*amO3*amO3)
+
+	tGases := math.Exp(-0.0127 * math.Pow(amP, 0.26))
+
+	amH2O := am * m.PrecipitableWater
+	tWater := 1 - 2.4959*amH2O/(math.Pow(1+79.034*amH2O, 0.6828)+6.385*amH2O)
+
+	tau := 0.2758*m.AOD380 + 0.35*m.AOD500
+	tAerosol := math.Exp(-math.Pow(tau, 0.873) * (1 + tau - math.Pow(tau, 0.7088)) * math.Pow(am, 0.9108))
+	tAA := 1 - 0.1*(1-am+math.Pow(am, 1.06))*(1-tAerosol)
+	rs := 0.0685 + (1-m.Asymmetry)*(1-tAerosol/tAA)
+
+	dni := 0.9662 * e0 * tAerosol * tWater * tGases * tOzone * tRayleigh
+	beamH := dni * cosZ
+	scattered := e0 * cosZ * 0.79 * tOzone * tGases * tWater * tAA *
+		(0.5*(1-tRayleigh) + m.Asymmetry*(1-tAerosol/tAA)) / (1 - am + math.Pow(am, 1.02))
+
+	ghi := (beamH + scattered) / (1 - m.Albedo*rs)
+
+	return ClearSky{GHI: ghi, DNI: dni, DHI: ghi - beamH}
+}
+
+// ClearSkyIndex compares measured irradiance with a clear-sky model. Each index is measured over
+// modeled irradiance, or MissingValue if the measurement is missing or the modeled value is too
+// small for a meaningful ratio.
+type ClearSkyIndex struct {
+	Timestamp time.Time `json:"timestamp"`
+	ClearSky  ClearSky  `json:"clear_sky"`
+	GHI       float64   `json:"ghi_index"`
+	DNI       float64   `json:"dni_index"`
+	DHI       float64   `json:"dhi_index"`
+}
+
+// minClearSkyIrradiance avoids dividing by tiny modeled values around sunrise and sunset.
+const minClearSkyIrradiance = 10.0
+
+func clearSkyRatio(measured float64, qc int, modeled float64) float64 {
+	if IsMissing(measured, qc) || modeled == MissingValue || modeled < minClearSkyIrradiance {
+		return MissingValue
+	}
+	return measured / modeled
+}
+
+// ClearSky evaluates model for every entry at the middle of its averaging minute.
+func (s Station) ClearSky(model ClearSkyModel) []ClearSky {
+	out := make([]ClearSky, len(s.Entries))
+	for i, d := range s.Entries {
+		out[i] = model.ClearSky(d.Timestamp.Add(-30*time.Second), s.LocatedAt)
+	}
+	return out
+}
+
+// ClearSkyIndex computes the clear-sky index series for every entry.
+func (s Station) ClearSkyIndex(model ClearSkyModel) []ClearSkyIndex {
+	cs := s.ClearSky(model)
+	out := make([]ClearSkyIndex, len(s.Entries))
+	for i, d := range s.Entries {
+		out[i] = ClearSkyIndex{
+			Timestamp: d.Timestamp,
+			ClearSky:  cs[i],
+			GHI:       clearSkyRatio(d.DownwellingSolar, d.QC.DownwellingSolar, cs[i].GHI),
+			DNI:       clearSkyRatio(d.DirectNormalSolar, d.QC.DirectNormalSolar, cs[i].DNI),
+			DHI:       clearSkyRatio(d.DownwellingDiffuseSolar, d.QC.DownwellingDiffuseSolar, cs[i].DHI),
+		}
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package surfrad
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var desertRock = Location{Latitude: 36.62, Longitude: -116.02, Elevation: 1007}
+
+func TestClearSkyModels(t *testing.T) {
+	noon := time.Date(2024, 6, 21, 19, 45, 0, 0, time.UTC)
+	night := time.Date(2024, 6, 21, 8, 0, 0, 0, time.UTC)
+	cosZ := math.Cos(desertRock.SunPosition(noon).ApparentZenith * degToRad)
+
+	models := []struct {
+		name  string
+		model ClearSkyModel
+	}{
+		{"haurwitz", Haurwitz{}},
+		{"ineichen", NewIneichen(3)},
+		{"bird", DefaultBird()},
+		{"bird zero value", Bird{}},
+	}
+
+	for _, m := range models {
+		t.Run(m.name, func(t *testing.T) {
+			cs := m.model.ClearSky(noon, desertRock)
+			if cs.GHI < 900 || cs.GHI > 1150 {
+				t.Errorf("implausible summer midday GHI: %v", cs.GHI)
+			}
+			if cs.DNI != MissingValue {
+				if cs.DNI < 800 || cs.DNI > 1100 {
+					t.Errorf("implausible summer midday DNI: %v", cs.DNI)
+				}
+				if closure := cs.DNI*cosZ + cs.DHI; math.Abs(closure-cs.GHI) > 1e-6 {
+					t.Errorf("components don't close: %v + %v != %v", cs.DNI*cosZ, cs.DHI, cs.GHI)
+				}
+			}
+
+			if cs = m.model.ClearSky(night, desertRock); cs.GHI != 0 {
+				t.Errorf("expected no irradiance at night, got %+v", cs)
+			}
+		})
+	}
+}
+
+func TestBirdAlbedo(t *testing.T) {
+	noon := time.Date(2024, 6, 21, 19, 45, 0, 0, time.UTC)
+	black, typical := DefaultBird(), DefaultBird()
+	black.Albedo = 0
+	unset := DefaultBird()
+	unset.Albedo = -1
+
+	ghi := func(m Bird) float64 { return m.ClearSky(noon, desertRock).GHI }
+	if ghi(black) >= ghi(typical) {
+		t.Errorf("zero albedo should reflect less back down: %v >= %v", ghi(black), ghi(typical))
+	}
+	if ghi(unset) != ghi(typical) {
+		t.Errorf("negative albedo should take the default: %v != %v", ghi(unset), ghi(typical))
+	}
+}
+
+func TestHaurwitzOverhead(t *testing.T) {
+	// find a time with the sun nearly overhead on the equator at the equinox
+	loc := Location{}
+	ts := time.Date(2024, 3, 20, 12, 7, 0, 0, time.UTC)
+	zen := loc.SunPosition(ts).ApparentZenith
+	cosZ := math.Cos(zen * degToRad)
+	expected := 1098 * cosZ * math.Exp(-0.059/cosZ)
+	if got := (Haurwitz{}).ClearSky(ts, loc).GHI; math.Abs(got-expected) > 1e-9 || got < 1030 {
+		t.Errorf("Haurwitz GHI == %v, expected %v", got, expected)
+	}
+}
+
+func TestIneichenTurbidity(t *testing.T) {
+	ts := time.Date(2024, 6, 21, 19, 45, 0, 0, time.UTC)
+	clean := NewIneichen(2).ClearSky(ts, desertRock)
+	hazy := NewIneichen(5).ClearSky(ts, desertRock)
+	if hazy.DNI >= clean.DNI || hazy.GHI >= clean.GHI || hazy.DHI <= clean.DHI {
+		t.Errorf("higher turbidity should reduce DNI and GHI and raise DHI: clean %+v, hazy %+v", clean, hazy)
+	}
+
+	high := NewIneichen(3).ClearSky(ts, Location{Latitude: desertRock.Latitude, Longitude: desertRock.Longitude, Elevation: 3000})
+	low := NewIneichen(3).ClearSky(ts, Location{Latitude: desertRock.Latitude, Longitude: desertRock.Longitude, Elevation: 0})
+	if high.DNI <= low.DNI {
+		t.Errorf("DNI should increase with elevation: %v <= %v", high.DNI, low.DNI)
+	}
+}
+
+func TestStationClearSkyIndex(t *testing.T) {
+	station := readTestStation(t)
+
+	idx := station.ClearSkyIndex(NewIneichen(3))
+	if len(idx) != station.Len() {
+		t.Fatalf("expected one index per entry")
+	}
+
+	for i, ci := range idx {
+		d := station.Entries[i]
+		switch {
+		case d.SolarZenithAngle > 95 && ci.GHI != MissingValue:
+			t.Errorf("%s: expected missing index at night, got %v", ci.Timestamp, ci.GHI)
+		case d.SolarZenithAngle < 80 && ci.GHI != MissingValue && (ci.GHI < 0 || ci.GHI > 2):
+			t.Errorf("%s: implausible clear-sky index %v", ci.Timestamp, ci.GHI)
+		}
+	}
+
+	// 2024-02-17 was overcast at Desert Rock
+	if noon := idx[20*60]; noon.GHI == MissingValue || noon.GHI > 0.7 {
+		t.Errorf("unexpected midday clear-sky index: %+v", noon)
+	}
+}
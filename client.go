@@ -0,0 +1,229 @@
+package surfrad
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultBaseURL = "https://gml.noaa.gov/aftp/data/radiation/surfrad/"
+
+var (
+	ErrNotFound     = errors.New("not found in archive")
+	ErrInvalidRange = errors.New("end is before start")
+)
+
+// Client downloads daily files from the SURFRAD archive.
+type Client struct {
+	// BaseURL is the archive root containing one directory per station, e.g. DefaultBaseURL.
+	BaseURL    string
+	HTTPClient *http.Client
+
+	// Retries is how many additional attempts are made after a failed request.
+	// Only network errors and 5xx/429 responses are retried.
+	Retries    int
+	RetryDelay time.Duration
+
+	// Concurrency limits the number of simultaneous downloads in Fetch.
+	Concurrency int
+}
+
+func NewClient() *Client {
+	return &Client{
+		BaseURL:     DefaultBaseURL,
+		HTTPClient:  &http.Client{Timeout: 2 * time.Minute},
+		Retries:     3,
+		RetryDelay:  time.Second,
+		Concurrency: 4,
+	}
+}
+
+// FileName returns the archive file name for a station day, e.g. dra24048.dat.
+func FileName(sid StationID, day time.Time) string {
+	day = day.UTC()
+	return fmt.Sprintf("%s%02d%03d.dat", sid, day.Year()%100, day.YearDay())
+}
+
+// URL returns the archive URL of the daily file for sid on the given (UTC) day.
+func (c *Client) URL(sid StationID, day time.Time) (string, error) {
+	dir, ok := GetStationDirectory(sid)
+	if !ok {
+		return "", fmt.Errorf("invalid or unknown station id: %s", sid)
+	}
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	return fmt.Sprintf("%s/%s/%d/%s", strings.TrimSuffix(base, "/"), dir, day.UTC().Year(), FileName(sid, day)), nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+type statusError struct {
+	url    string
+	status int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status fetching %s: %d %s", e.url, e.status, http.StatusText(e.status))
+}
+
+func (e *statusError) temporary() bool {
+	return e.status >= 500 || e.status == http.StatusTooManyRequests
+}
+
+// do performs a single request, returning the response with a fully read body.
+func (c *Client) do(ctx context.Context, method, url string, header http.Header) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return resp, nil, fmt.Errorf("%w: %s", ErrNotFound, url)
+	case resp.StatusCode == http.StatusNotModified:
+		return resp, nil, nil
+	case resp.StatusCode != http.StatusOK:
+		return resp, nil, &statusError{url: url, status: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+// request performs a request with retries, honoring context cancellation between attempts.
+func (c *Client) request(ctx context.Context, method, url string, header http.Header) (*http.Response, []byte, error) {
+	var (
+		resp *http.Response
+		body []byte
+		err  error
+	)
+
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if attempt > 0 {
+			debugPrint("retrying %s (attempt %d): %v\n", url, attempt+1, err)
+			select {
+			case <-ctx.Done():
+				return nil, nil, errors.Join(ctx.Err(), err)
+			case <-time.After(c.RetryDelay * time.Duration(attempt)):
+			}
+		}
+
+		resp, body, err = c.do(ctx, method, url, header)
+
+		var se *statusError
+		switch {
+		case err == nil, errors.Is(err, ErrNotFound), ctx.Err() != nil:
+			return resp, body, err
+		case errors.As(err, &se) && !se.temporary():
+			return resp, body, err
+		}
+	}
+
+	return resp, body, err
+}
+
+// Download returns the raw contents of the daily file for sid on day.
+func (c *Client) Download(ctx context.Context, sid StationID, day time.Time) ([]byte, error) {
+	url, err := c.URL(sid, day)
+	if err != nil {
+		return nil, err
+	}
+	_, body, err := c.request(ctx, http.MethodGet, url, nil)
+	return body, err
+}
+
+// FetchDay downloads and parses the daily file for sid on day.
+func (c *Client) FetchDay(ctx context.Context, sid StationID, day time.Time) (Station, error) {
+	body, err := c.Download(ctx, sid, day)
+	if err != nil {
+		return Station{}, err
+	}
+	return ReadData(bytes.NewReader(body))
+}
+
+// Days returns midnight UTC of every day from start through end inclusive.
+func Days(start, end time.Time) []time.Time {
+	start = start.UTC().Truncate(24 * time.Hour)
+	end = end.UTC().Truncate(24 * time.Hour)
+
+	var days []time.Time
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	return days
+}
+
+// Fetch downloads and parses every daily file for sid from start through end inclusive,
+// with at most Concurrency downloads in flight. Stations are returned in day order. Days that could
+// not be downloaded are omitted, and all errors (including per-record parse errors) are joined.
+func (c *Client) Fetch(ctx context.Context, sid StationID, start, end time.Time) ([]Station, error) {
+	if end.Before(start) {
+		return nil, ErrInvalidRange
+	}
+	if !sid.Valid() {
+		return nil, fmt.Errorf("invalid or unknown station id: %s", sid)
+	}
+
+	days := Days(start, end)
+	results := make([]Station, len(days))
+	errs := make([]error, len(days))
+
+	limit := c.Concurrency
+	if limit < 1 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+
+	for i, day := range days {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, day time.Time) {
+			defer func() { <-sem; wg.Done() }()
+			station, err := c.FetchDay(ctx, sid, day)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", day.Format(time.DateOnly), err)
+			}
+			results[i] = station
+		}(i, day)
+	}
+
+	wg.Wait()
+
+	stations := make([]Station, 0, len(days))
+	for i := range days {
+		if errs[i] == nil || results[i].Len() > 0 {
+			stations = append(stations, results[i])
+		}
+	}
+
+	return stations, errors.Join(errs...)
+}
@@ -0,0 +1,144 @@
+package surfrad
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileNameAndURL(t *testing.T) {
+	c := &Client{BaseURL: "http://example.com/surfrad/"}
+	day := time.Date(2024, time.February, 17, 13, 0, 0, 0, time.UTC)
+
+	if got := FileName(StationIDDesertRock, day); got != "dra24048.dat" {
+		t.Errorf("FileName() == %q, expected dra24048.dat", got)
+	}
+
+	url, err := c.URL(StationIDDesertRock, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "http://example.com/surfrad/Desert_Rock_NV/2024/dra24048.dat" {
+		t.Errorf("URL() == %q", url)
+	}
+
+	if _, err = c.URL(StationID{'x', 'y', 'z'}, day); err == nil {
+		t.Error("URL() for an invalid station should fail")
+	}
+}
+
+func newArchiveServer(t *testing.T, failFirst int32) (*httptest.Server, *int32, *int32) {
+	t.Helper()
+	body, err := os.ReadFile("testdata/dra24048.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests, inFlight, maxInFlight int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		if atomic.AddInt32(&requests, 1) <= failFirst {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/Desert_Rock_NV/2024/dra24048.dat", "/Desert_Rock_NV/2024/dra24049.dat", "/Desert_Rock_NV/2024/dra24050.dat":
+			_, _ = w.Write(body)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &requests, &maxInFlight
+}
+
+func TestClientFetchDayRetries(t *testing.T) {
+	srv, requests, _ := newArchiveServer(t, 2)
+	c := &Client{BaseURL: srv.URL, HTTPClient: srv.Client(), Retries: 2, RetryDelay: time.Millisecond, Concurrency: 1}
+
+	station, err := c.FetchDay(context.Background(), StationIDDesertRock, time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if station.Len() != 1440 {
+		t.Errorf("FetchDay() returned %d entries, expected 1440", station.Len())
+	}
+	if got := atomic.LoadInt32(requests); got != 3 {
+		t.Errorf("expected 3 requests, got %d", got)
+	}
+}
+
+func TestClientFetchDayNotFound(t *testing.T) {
+	srv, requests, _ := newArchiveServer(t, 0)
+	c := &Client{BaseURL: srv.URL, HTTPClient: srv.Client(), Retries: 3, RetryDelay: time.Millisecond}
+
+	_, err := c.FetchDay(context.Background(), StationIDBondville, time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("404 should not be retried, got %d requests", got)
+	}
+}
+
+func TestClientFetchRange(t *testing.T) {
+	srv, _, maxInFlight := newArchiveServer(t, 0)
+	c := &Client{BaseURL: srv.URL, HTTPClient: srv.Client(), RetryDelay: time.Millisecond, Concurrency: 2}
+
+	start := time.Date(2024, 2, 16, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 2, 19, 12, 0, 0, 0, time.UTC)
+
+	stations, err := c.Fetch(context.Background(), StationIDDesertRock, start, end)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the missing day to be reported, got %v", err)
+	}
+	if len(stations) != 3 {
+		t.Errorf("Fetch() returned %d stations, expected 3", len(stations))
+	}
+	if got := atomic.LoadInt32(maxInFlight); got > 2 {
+		t.Errorf("concurrency limit exceeded: %d requests in flight", got)
+	}
+
+	if _, err = c.Fetch(context.Background(), StationIDDesertRock, end, start); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("expected ErrInvalidRange, got %v", err)
+	}
+}
+
+func TestClientFetchCanceled(t *testing.T) {
+	srv, _, _ := newArchiveServer(t, 100)
+	c := &Client{BaseURL: srv.URL, HTTPClient: srv.Client(), Retries: 10, RetryDelay: time.Hour, Concurrency: 1}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Fetch(ctx, StationIDDesertRock, time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Fetch() did not honor context cancellation")
+	}
+}
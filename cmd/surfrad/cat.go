@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func runCat(args []string, std stdio) error {
+	fs := flag.NewFlagSet("cat", flag.ContinueOnError)
+	fs.SetOutput(std.err)
+	cols := fs.String("columns", "dw_solar,direct_n,diffuse,temp", "comma separated columns, any of: "+columnNames())
+	startStr := fs.String("start", "", "only records at or after this time (UTC)")
+	endStr := fs.String("end", "", "only records before this time (UTC)")
+	strict := fs.Bool("strict", false, "fail on any parse error instead of warning")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	start, err := parseTime(*startStr)
+	if err != nil {
+		return err
+	}
+	end, err := parseTime(*endStr)
+	if err != nil {
+		return err
+	}
+	selected, err := selectColumns(*cols)
+	if err != nil {
+		return err
+	}
+
+	inputs, err := readInputs(fs.Args(), std, *strict)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(std.out, 0, 4, 2, ' ', tabwriter.AlignRight)
+
+	header := []string{"timestamp"}
+	for _, c := range selected {
+		header = append(header, c.Name)
+	}
+	_, _ = fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+
+	for _, in := range inputs {
+		entries := in.station.Between(start, end)
+		for i := range entries {
+			d := &entries[i]
+			row := []string{d.Timestamp.Format(time.RFC3339)}
+			for _, c := range selected {
+				v := formatValue(c, d)
+				if v == "" {
+					v = "-"
+				}
+				row = append(row, v)
+			}
+			_, _ = fmt.Fprintln(tw, strings.Join(row, "\t")+"\t")
+		}
+	}
+
+	return tw.Flush()
+}
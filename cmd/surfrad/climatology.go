@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"git.tcp.direct/kayos/surfrad"
+	"git.tcp.direct/kayos/surfrad/stats"
+)
+
+func runClimatology(args []string, std stdio) error {
+	fs := flag.NewFlagSet("climatology", flag.ContinueOnError)
+	fs.SetOutput(std.err)
+	out := fs.String("o", "", "file to write the climatology to, as gob if it ends in .gob and JSON otherwise")
+	vars := fs.String("columns", "", "comma separated variables to include, default all measured: "+columnNames())
+	local := fs.Bool("local", false, "bin hours and days in the station's local standard time")
+	good := fs.Bool("good", false, "only values flagged good")
+	pcts := fs.String("p", "10,25,75,90", "comma separated percentiles bounding the bands")
+	window := fs.Int("window", 7, "days either side pooled into each day of year")
+	coverage := fs.Float64("coverage", 0.5, "share of an hour's records needed for its mean to count")
+	strict := fs.Bool("strict", false, "fail on any parse error instead of warning")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("-o is required")
+	}
+	if *window < 0 {
+		return fmt.Errorf("invalid window %d", *window)
+	}
+
+	opts := stats.DefaultClimatologyOptions()
+	opts.LocalTime, opts.GoodQC, opts.DayWindow, opts.MinCoverage = *local, *good, *window, *coverage
+	if strings.TrimSpace(*vars) != "" {
+		for _, name := range strings.Split(*vars, ",") {
+			opts.Variables = append(opts.Variables, strings.TrimSpace(name))
+		}
+	}
+	pct, err := parsePercentiles(*pcts)
+	if err != nil {
+		return err
+	}
+	opts.Percentiles = pct
+
+	b, err := stats.NewClimatologyBuilder(opts)
+	if err != nil {
+		return err
+	}
+	inputs, err := readInputs(fs.Args(), std, *strict)
+	if err != nil {
+		return err
+	}
+	for _, in := range inputs {
+		if err = b.Add(in.station); err != nil {
+			return fmt.Errorf("%s: %w", in.name, err)
+		}
+	}
+	c := b.Build()
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err = c.Encode(f, stats.EncodingForPath(*out)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(std.err, "%s: %d days from %s to %s\n", c.Station, c.Days,
+		c.Start.Format("2006-01-02"), c.End.Format("2006-01-02"))
+	return nil
+}
+
+func runAnomalies(args []string, std stdio) error {
+	fs := flag.NewFlagSet("anomalies", flag.ContinueOnError)
+	fs.SetOutput(std.err)
+	path := fs.String("c", "", "climatology file written by the climatology command")
+	outside := fs.Bool("outside", false, "only hours outside the monthly or day of year band")
+	asJSON := fs.Bool("json", false, "write the anomalies as JSON")
+	strict := fs.Bool("strict", false, "fail on any parse error instead of warning")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New("-c is required")
+	}
+
+	f, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	c, err := stats.DecodeClimatology(f, stats.EncodingForPath(*path))
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+
+	inputs, err := readInputs(fs.Args(), std, *strict)
+	if err != nil {
+		return err
+	}
+	stations := make([]surfrad.Station, len(inputs))
+	for i, in := range inputs {
+		stations[i] = in.station
+	}
+	// days spanning two files in local time are scored whole
+	if stations, err = mergeStations(stations, surfrad.KeepFirst, std); err != nil {
+		return err
+	}
+
+	var all stats.Anomalies
+	for _, st := range stations {
+		anomalies, err := c.Anomalies(st)
+		if err != nil {
+			return err
+		}
+		for _, a := range anomalies {
+			if !*outside || a.Monthly.Outside != 0 || a.DayOfYear.Outside != 0 {
+				all = append(all, a)
+			}
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(std.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(all)
+	}
+	return all.WriteText(std.out)
+}
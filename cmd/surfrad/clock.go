@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+var clocks = []string{"utc", "local", "solar"}
+
+// clock converts a record's timestamp for presentation and binning.
+type clock func(st surfrad.Station, t time.Time) time.Time
+
+// parseClock returns the clock for utc, local (the station's local standard time) or solar
+// (apparent solar time at the station).
+func parseClock(name string) (clock, error) {
+	switch name {
+	case "", "utc":
+		return func(_ surfrad.Station, t time.Time) time.Time { return t.UTC() }, nil
+	case "local":
+		return func(st surfrad.Station, t time.Time) time.Time { return t.In(st.TimeZone()) }, nil
+	case "solar":
+		return func(st surfrad.Station, t time.Time) time.Time {
+			return surfrad.TrueSolarTime(t, st.LocatedAt.Longitude)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown time %q, expected one of %v", name, clocks)
+	}
+}
+
+// applyClock returns a copy of the station with every timestamp converted by c.
+func applyClock(st surfrad.Station, c clock) surfrad.Station {
+	entries := make([]surfrad.Data, len(st.Entries))
+	for i, d := range st.Entries {
+		d.Timestamp = c(st, d.Timestamp)
+		entries[i] = d
+	}
+	st.Entries = entries
+	return st
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"strings"
+
+	"git.tcp.direct/kayos/surfrad"
+	"git.tcp.direct/kayos/surfrad/stats"
+)
+
+func runCompare(args []string, std stdio) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	fs.SetOutput(std.err)
+	vars := fs.String("columns", "", "comma separated variables to compare, default all measured: "+columnNames())
+	lag := fs.Duration("lag", 0, "compute cross-correlations for lags up to this long, e.g. 30m")
+	good := fs.Bool("good", false, "only values flagged good at both stations")
+	asJSON := fs.Bool("json", false, "write the comparison as JSON")
+	strict := fs.Bool("strict", false, "fail on any parse error instead of warning")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := stats.CompareOptions{MaxLag: *lag, GoodQC: *good}
+	if strings.TrimSpace(*vars) != "" {
+		for _, name := range strings.Split(*vars, ",") {
+			opts.Variables = append(opts.Variables, strings.TrimSpace(name))
+		}
+	}
+
+	inputs, err := readInputs(fs.Args(), std, *strict)
+	if err != nil {
+		return err
+	}
+
+	stations := make([]surfrad.Station, len(inputs))
+	for i, in := range inputs {
+		stations[i] = in.station
+	}
+	if stations, err = mergeStations(stations, surfrad.KeepFirst, std); err != nil {
+		return err
+	}
+
+	c, err := stats.Compare(opts, stations...)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(std.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c)
+	}
+	return c.WriteText(std.out)
+}
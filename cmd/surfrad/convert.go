@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"strconv"
+	"time"
+
+	"git.tcp.direct/kayos/surfrad"
+	"git.tcp.direct/kayos/surfrad/storage"
+)
+
+var formats = []string{"csv", "tsv", "json", "jsonl", "sqlite"}
+
+func runConvert(args []string, std stdio) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(std.err)
+	to := fs.String("to", "csv", fmt.Sprintf("output format, one of %v", formats))
+	out := fs.String("o", "-", "output file, - for stdout (sqlite requires a file)")
+	cols := fs.String("columns", "", "comma separated columns for csv/tsv, any of: "+columnNames())
+	withQC := fs.Bool("qc", false, "include QC flag columns in csv/tsv output")
+	startStr := fs.String("start", "", "only records at or after this time (UTC)")
+	endStr := fs.String("end", "", "only records before this time (UTC)")
+	clearOnly := fs.Bool("clear-sky", false, "only records detected as clear-sky (Reno & Hansen)")
+	timeStr := fs.String("time", "utc", fmt.Sprintf("write timestamps in this time, one of %v", clocks))
+	strict := fs.Bool("strict", false, "fail on any parse error instead of warning")
+	unitsStr := fs.String("units", "", "convert variables in csv/tsv output, e.g. temp=degF,windspd=knot; see the variables command")
+	fill := fs.Duration("fill", 0, "fill runs of missing values and records up to this long, e.g. 15m")
+	mergeStr := fs.String("merge", "", "merge inputs of the same station, resolving duplicate timestamps by first, last, error or prefer-good-qc")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	start, err := parseTime(*startStr)
+	if err != nil {
+		return err
+	}
+	end, err := parseTime(*endStr)
+	if err != nil {
+		return err
+	}
+	selected, err := selectColumns(*cols)
+	if err != nil {
+		return err
+	}
+	clk, err := parseClock(*timeStr)
+	if err != nil {
+		return err
+	}
+	units, err := surfrad.ParseUnits(*unitsStr)
+	if err != nil {
+		return err
+	}
+	// only delimited output labels its columns with units; json and sqlite carry values under the native
+	// field names and storage schema, where converted values would pass for native ones
+	if len(units) > 0 && *to != "csv" && *to != "tsv" {
+		return fmt.Errorf("-units only applies to csv and tsv output, not %s", *to)
+	}
+	selected = units.Variables(selected)
+
+	if *to == "sqlite" && *out == "-" {
+		return errors.New("sqlite output requires -o")
+	}
+
+	inputs, err := readInputs(fs.Args(), std, *strict)
+	if err != nil {
+		return err
+	}
+
+	stations := make([]surfrad.Station, len(inputs))
+	for i, in := range inputs {
+		stations[i] = in.station
+	}
+	if *mergeStr != "" {
+		policy, err := surfrad.ParseDuplicatePolicy(*mergeStr)
+		if err != nil {
+			return err
+		}
+		if stations, err = mergeStations(stations, policy, std); err != nil {
+			return err
+		}
+	}
+
+	for i := range stations {
+		stations[i] = filterStation(stations[i], start, end)
+		if *fill > 0 {
+			opts := surfrad.DefaultFillOptions()
+			opts.MaxGap = *fill
+			filled, prov, err := stations[i].Fill(opts)
+			if err != nil {
+				return err
+			}
+			inserted := len(filled.Entries) - len(stations[i].Entries)
+			var values int
+			for _, p := range prov {
+				values += bits.OnesCount32(uint32(p &^ surfrad.Inserted))
+			}
+			_, _ = fmt.Fprintf(std.err, "%s: filled %d values, inserted %d records\n", filled.StationName, values, inserted)
+			stations[i] = filled
+		}
+		if *clearOnly {
+			if stations[i], err = stations[i].ClearSkyOnly(surfrad.DefaultClearSkyDetection()); err != nil {
+				return err
+			}
+		}
+		if stations[i], err = stations[i].ConvertUnits(units); err != nil {
+			return err
+		}
+		stations[i] = applyClock(stations[i], clk)
+	}
+
+	if *to == "sqlite" {
+		return writeSQLite(*out, stations)
+	}
+
+	var w io.Writer = std.out
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+
+	switch *to {
+	case "csv":
+		err = writeDelimited(bw, ',', stations, selected, *withQC)
+	case "tsv":
+		err = writeDelimited(bw, '\t', stations, selected, *withQC)
+	case "json":
+		enc := json.NewEncoder(bw)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(stations)
+	case "jsonl":
+		err = writeJSONLines(bw, stations)
+	default:
+		return fmt.Errorf("unknown format %q, expected one of %v", *to, formats)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// mergeStations merges the inputs of each station, in order of first appearance, reporting header drift
+// and dropped duplicates as warnings.
+func mergeStations(stations []surfrad.Station, policy surfrad.DuplicatePolicy, std stdio) ([]surfrad.Station, error) {
+	var (
+		names  []surfrad.StationName
+		groups = make(map[surfrad.StationName][]surfrad.Station)
+	)
+	for _, st := range stations {
+		if _, ok := groups[st.StationName]; !ok {
+			names = append(names, st.StationName)
+		}
+		groups[st.StationName] = append(groups[st.StationName], st)
+	}
+
+	merged := make([]surfrad.Station, 0, len(names))
+	for _, name := range names {
+		st, report, err := surfrad.Merge(surfrad.MergeOptions{Duplicates: policy}, groups[name]...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, drift := range report.Drift {
+			_, _ = fmt.Fprintf(std.err, "warning: %s: header drift: %s\n", name, drift)
+		}
+		if report.Duplicates > 0 {
+			_, _ = fmt.Fprintf(std.err, "warning: %s: %d duplicate records resolved by %s\n", name, report.Duplicates, policy)
+		}
+		merged = append(merged, st)
+	}
+
+	return merged, nil
+}
+
+func filterStation(st surfrad.Station, start, end time.Time) surfrad.Station {
+	if start.IsZero() && end.IsZero() {
+		return st
+	}
+	st.Entries = st.Between(start, end)
+	return st
+}
+
+func formatValue(c surfrad.Variable, d *surfrad.Data) string {
+	if c.Missing(d) {
+		return ""
+	}
+	return strconv.FormatFloat(c.Value(d), 'f', -1, 64)
+}
+
+func writeDelimited(w io.Writer, comma rune, stations []surfrad.Station, cols []surfrad.Variable, withQC bool) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	header := []string{"station", "timestamp"}
+	for _, c := range cols {
+		name := c.Name
+		// converted columns carry their unit
+		if v, _ := surfrad.LookupVariable(c.Name); v.Unit != c.Unit {
+			name += " (" + c.Unit + ")"
+		}
+		header = append(header, name)
+		if withQC && c.QC != nil {
+			header = append(header, "qc_"+c.Name)
+		}
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, st := range stations {
+		sid, _ := surfrad.GetStationID(st.StationName)
+		for i := range st.Entries {
+			d := &st.Entries[i]
+			record := []string{sid.String(), d.Timestamp.Format(time.RFC3339)}
+			for _, c := range cols {
+				record = append(record, formatValue(c, d))
+				if withQC && c.QC != nil {
+					record = append(record, strconv.Itoa(c.QC(d)))
+				}
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+type jsonRecord struct {
+	Station string `json:"station"`
+	surfrad.Data
+}
+
+func writeJSONLines(w io.Writer, stations []surfrad.Station) error {
+	enc := json.NewEncoder(w)
+	for _, st := range stations {
+		sid, _ := surfrad.GetStationID(st.StationName)
+		for _, d := range st.Entries {
+			if err := enc.Encode(jsonRecord{Station: sid.String(), Data: d}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeSQLite(path string, stations []surfrad.Station) error {
+	store, err := storage.Open(path)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, st := range stations {
+		if err = store.Ingest(context.Background(), st); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err = store.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+func runGaps(args []string, std stdio) error {
+	fs := flag.NewFlagSet("gaps", flag.ContinueOnError)
+	fs.SetOutput(std.err)
+	vars := fs.String("columns", "", "comma separated variables to report, default all measured: "+columnNames())
+	local := fs.Bool("local", false, "report days and months in the station's local standard time")
+	asJSON := fs.Bool("json", false, "write the report as JSON")
+	strict := fs.Bool("strict", false, "fail on any parse error instead of warning")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := surfrad.GapOptions{LocalTime: *local}
+	if strings.TrimSpace(*vars) != "" {
+		for _, name := range strings.Split(*vars, ",") {
+			opts.Variables = append(opts.Variables, strings.TrimSpace(name))
+		}
+	}
+
+	inputs, err := readInputs(fs.Args(), std, *strict)
+	if err != nil {
+		return err
+	}
+
+	stations := make([]surfrad.Station, len(inputs))
+	for i, in := range inputs {
+		stations[i] = in.station
+	}
+	// daily files are analyzed together, so gaps spanning midnight are found
+	if stations, err = mergeStations(stations, surfrad.KeepFirst, std); err != nil {
+		return err
+	}
+
+	reports := make([]surfrad.Completeness, 0, len(stations))
+	for _, st := range stations {
+		c, err := st.Completeness(opts)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, c)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(std.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	}
+
+	for i, c := range reports {
+		if i > 0 {
+			_, _ = fmt.Fprintln(std.out)
+		}
+		if err = c.WriteText(std.out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
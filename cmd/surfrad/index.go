@@ -0,0 +1,184 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+// fileIndex records which daily .dat file covers which station day, based on the archive's
+// file naming scheme (e.g. dra24048.dat), so requests only parse the files they need.
+type fileIndex struct {
+	root string
+
+	mu      sync.RWMutex
+	days    map[surfrad.StationID]map[time.Time]string
+	headers map[surfrad.StationID]surfrad.Station // header only, no entries
+}
+
+func newFileIndex(root string) (*fileIndex, error) {
+	idx := &fileIndex{root: root}
+	return idx, idx.scan()
+}
+
+// parseFileName extracts the station and UTC day from an archive file name.
+func parseFileName(name string) (surfrad.StationID, time.Time, bool) {
+	var sid surfrad.StationID
+
+	name = strings.ToLower(filepath.Base(name))
+	if len(name) != len("dra24048.dat") || !strings.HasSuffix(name, ".dat") {
+		return sid, time.Time{}, false
+	}
+
+	copy(sid[:], []rune(name[:3]))
+	if !sid.Valid() {
+		return sid, time.Time{}, false
+	}
+
+	yy, err := strconv.Atoi(name[3:5])
+	if err != nil {
+		return sid, time.Time{}, false
+	}
+	jday, err := strconv.Atoi(name[5:8])
+	if err != nil || jday < 1 || jday > 366 {
+		return sid, time.Time{}, false
+	}
+
+	// the network started in 1993
+	year := 2000 + yy
+	if yy >= 90 {
+		year = 1900 + yy
+	}
+
+	return sid, time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, jday-1), true
+}
+
+func (idx *fileIndex) scan() error {
+	days := make(map[surfrad.StationID]map[time.Time]string)
+
+	err := filepath.WalkDir(idx.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		sid, day, ok := parseFileName(d.Name())
+		if !ok {
+			return nil
+		}
+		if days[sid] == nil {
+			days[sid] = make(map[time.Time]string)
+		}
+		days[sid][day] = path
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	idx.days = days
+	idx.headers = make(map[surfrad.StationID]surfrad.Station)
+	idx.mu.Unlock()
+
+	return nil
+}
+
+func (idx *fileIndex) stations() []surfrad.StationID {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	ids := make([]surfrad.StationID, 0, len(idx.days))
+	for sid := range idx.days {
+		ids = append(ids, sid)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+	return ids
+}
+
+// coverage returns the sorted days available for sid.
+func (idx *fileIndex) coverage(sid surfrad.StationID) []time.Time {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	days := make([]time.Time, 0, len(idx.days[sid]))
+	for day := range idx.days[sid] {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+func (idx *fileIndex) path(sid surfrad.StationID, day time.Time) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	p, ok := idx.days[sid][day]
+	return p, ok
+}
+
+func readFile(path string) (surfrad.Station, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return surfrad.Station{}, err
+	}
+	defer f.Close()
+	return surfrad.ReadData(f)
+}
+
+// header returns the station header from the most recent file for sid.
+func (idx *fileIndex) header(sid surfrad.StationID) (surfrad.Station, bool) {
+	idx.mu.RLock()
+	st, ok := idx.headers[sid]
+	idx.mu.RUnlock()
+	if ok {
+		return st, true
+	}
+
+	days := idx.coverage(sid)
+	if len(days) == 0 {
+		return surfrad.Station{}, false
+	}
+
+	path, _ := idx.path(sid, days[len(days)-1])
+	st, _ = readFile(path)
+	st.Entries = nil
+
+	idx.mu.Lock()
+	idx.headers[sid] = st
+	idx.mu.Unlock()
+
+	return st, true
+}
+
+// load parses only the files covering [start, end) and returns the entries within it.
+func (idx *fileIndex) load(sid surfrad.StationID, start, end time.Time) (surfrad.Station, error) {
+	st, _ := idx.header(sid)
+
+	var errs []error
+	for _, day := range surfrad.Days(start, end.Add(-time.Nanosecond)) {
+		path, ok := idx.path(sid, day)
+		if !ok {
+			continue
+		}
+		parsed, err := readFile(path)
+		if err != nil && parsed.Len() == 0 {
+			errs = append(errs, err)
+			continue
+		}
+		st.Entries = append(st.Entries, parsed.Between(start, end)...)
+	}
+
+	if len(errs) > 0 && st.Len() == 0 {
+		return st, errs[0]
+	}
+
+	return st, nil
+}
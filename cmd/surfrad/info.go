@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+func runInfo(args []string, std stdio) error {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	fs.SetOutput(std.err)
+	strict := fs.Bool("strict", false, "fail on any parse error instead of warning")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	inputs, err := readInputs(fs.Args(), std, *strict)
+	if err != nil {
+		return err
+	}
+
+	for i, in := range inputs {
+		if i > 0 {
+			_, _ = fmt.Fprintln(std.out)
+		}
+		printInfo(std, in)
+	}
+
+	return nil
+}
+
+func printInfo(std stdio, in input) {
+	st := in.station
+	tw := tabwriter.NewWriter(std.out, 0, 4, 2, ' ', 0)
+
+	sid, _ := surfrad.GetStationID(st.StationName)
+
+	_, _ = fmt.Fprintf(tw, "file:\t%s\n", in.name)
+	_, _ = fmt.Fprintf(tw, "station:\t%s (%s)\n", st.StationName, sid)
+	_, _ = fmt.Fprintf(tw, "location:\t%.2f, %.2f, %d m\n", st.LocatedAt.Latitude, st.LocatedAt.Longitude, st.LocatedAt.Elevation)
+	_, _ = fmt.Fprintf(tw, "version:\t%d\n", st.Version)
+	_, _ = fmt.Fprintf(tw, "records:\t%d\n", st.Len())
+
+	if st.Len() == 0 {
+		_ = tw.Flush()
+		return
+	}
+
+	first, last := st.Entries[0].Timestamp, st.Entries[0].Timestamp
+	for _, e := range st.Entries {
+		if e.Timestamp.Before(first) {
+			first = e.Timestamp
+		}
+		if e.Timestamp.After(last) {
+			last = e.Timestamp
+		}
+	}
+
+	_, _ = fmt.Fprintf(tw, "span:\t%s to %s (%s)\n", first.Format(time.RFC3339), last.Format(time.RFC3339), last.Sub(first))
+	_, _ = fmt.Fprintf(tw, "missing:\t\n")
+
+	implausible := make(map[string]int)
+	for _, v := range st.OutOfRange() {
+		implausible[v.Variable]++
+	}
+
+	for _, c := range surfrad.Variables {
+		missing := 0
+		for i := range st.Entries {
+			if c.Missing(&st.Entries[i]) {
+				missing++
+			}
+		}
+		_, _ = fmt.Fprintf(tw, "  %s\t%.1f%%", c.Name, 100*float64(missing)/float64(st.Len()))
+		if n := implausible[c.Name]; n > 0 {
+			_, _ = fmt.Fprintf(tw, "\t%d out of range [%g, %g] %s", n, c.Min, c.Max, c.Unit)
+		}
+		_, _ = fmt.Fprintln(tw)
+	}
+
+	_ = tw.Flush()
+}
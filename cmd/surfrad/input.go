@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+type input struct {
+	name    string
+	station surfrad.Station
+}
+
+// expandArgs turns the positional arguments into a list of paths, expanding globs.
+// An empty list or "-" means stdin.
+func expandArgs(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return []string{"-"}, nil
+	}
+
+	var paths []string
+	for _, arg := range args {
+		if arg == "-" {
+			paths = append(paths, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no such file: %s", arg)
+		}
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+	}
+
+	return paths, nil
+}
+
+// readInputs parses every input with surfrad.ReadData. Parse errors are reported as warnings
+// unless strict is set, as SURFRAD files routinely contain a few malformed records.
+func readInputs(args []string, std stdio, strict bool) ([]input, error) {
+	paths, err := expandArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([]input, 0, len(paths))
+
+	for _, path := range paths {
+		var station surfrad.Station
+		name := path
+
+		if path == "-" {
+			name = "stdin"
+			station, err = surfrad.ReadData(std.in)
+		} else {
+			var f *os.File
+			if f, err = os.Open(path); err != nil {
+				return nil, err
+			}
+			station, err = surfrad.ReadData(f)
+			_ = f.Close()
+		}
+
+		if err != nil {
+			if strict {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			warn(std, name, err)
+		}
+
+		inputs = append(inputs, input{name: name, station: station})
+	}
+
+	return inputs, nil
+}
+
+// warn summarizes a (possibly joined) parse error on one line.
+func warn(std stdio, name string, err error) {
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) && len(joined.Unwrap()) > 1 {
+		errs := joined.Unwrap()
+		_, _ = fmt.Fprintf(std.err, "warning: %s: %d problems, first: %v\n", name, len(errs), errs[0])
+		return
+	}
+	_, _ = fmt.Fprintf(std.err, "warning: %s: %v\n", name, err)
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time: %q (want RFC3339 or 2006-01-02[T15:04])", s)
+}
+
+// selectColumns resolves a comma separated list of variable names. An empty list selects everything.
+func selectColumns(list string) ([]surfrad.Variable, error) {
+	var names []string
+	if strings.TrimSpace(list) != "" {
+		for _, name := range strings.Split(list, ",") {
+			names = append(names, strings.TrimSpace(name))
+		}
+	}
+	return surfrad.SelectVariables(names)
+}
+
+func columnNames() string {
+	return strings.Join(surfrad.VariableNames(), ",")
+}
+
+// parsePercentiles parses a comma separated list of percentiles. An empty list gives none.
+func parsePercentiles(list string) ([]float64, error) {
+	pcts := []float64{}
+	if strings.TrimSpace(list) == "" {
+		return pcts, nil
+	}
+	for _, s := range strings.Split(list, ",") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid percentile %q", s)
+		}
+		pcts = append(pcts, p)
+	}
+	return pcts, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type stdio struct {
+	in  io.Reader
+	out io.Writer
+	err io.Writer
+}
+
+type command struct {
+	usage string
+	run   func(args []string, std stdio) error
+}
+
+var commands = map[string]command{
+	"anomalies":   {"score hourly means against a climatology built by the climatology command", runAnomalies},
+	"climatology": {"build monthly and day of year hourly climatologies and save them as JSON or gob", runClimatology},
+	"info":        {"print header, record count, time span and missing percentages", runInfo},
+	"convert":     {"convert .dat files to csv, tsv, json, jsonl or sqlite", runConvert},
+	"cat":         {"print selected columns for a time range", runCat},
+	"serve":       {"serve a directory of .dat files over an HTTP JSON API", runServe},
+	"compare":     {"compare variables between stations: bias, rmse, correlation and lagged correlation", runCompare},
+	"stats":       {"print min, max, mean, median, spread and percentiles of each variable", runStats},
+	"gaps":        {"report gaps and per-variable availability by day and month", runGaps},
+	"variables":   {"list the variables with their units, file columns and plausible ranges", runVariables},
+}
+
+func usage(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "usage: surfrad <command> [flags] [file|glob|-]...\n\ncommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "  %-12s %s\n", name, commands[name].usage)
+	}
+	_, _ = fmt.Fprintf(w, "\nwith no files, or with -, input is read from stdin\n")
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) < 1 {
+		usage(stderr)
+		return 2
+	}
+
+	if args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+		usage(stdout)
+		return 0
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		_, _ = fmt.Fprintf(stderr, "unknown command: %s\n\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	if err := cmd.run(args[1:], stdio{in: stdin, out: stdout, err: stderr}); err != nil {
+		_, _ = fmt.Fprintf(stderr, "surfrad %s: %v\n", args[0], err)
+		return 1
+	}
+
+	return 0
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
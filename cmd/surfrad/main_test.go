@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"git.tcp.direct/kayos/surfrad"
+	"git.tcp.direct/kayos/surfrad/stats"
+)
+
+const testFile = "../../testdata/dra24048.dat"
+
+func runCLI(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestInfo(t *testing.T) {
+	out, errOut, code := runCLI(t, "", "info", testFile)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	for _, want := range []string{"Desert Rock (dra)", "records:", "1440", "2024-02-17T00:00:00Z", "dw_solar"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("info output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name  string
+		args  []string
+		check func(t *testing.T, out string)
+	}{
+		{"csv from stdin", []string{"convert", "-to", "csv", "-columns", "temp,rh"}, func(t *testing.T, out string) {
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if len(lines) != 1441 {
+				t.Errorf("expected 1441 lines, got %d", len(lines))
+			}
+			if lines[0] != "station,timestamp,temp,rh" {
+				t.Errorf("unexpected header: %s", lines[0])
+			}
+		}},
+		{"jsonl with range", []string{"convert", "-to", "jsonl", "-start", "2024-02-17T01:00", "-end", "2024-02-17T01:05"}, func(t *testing.T, out string) {
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if len(lines) != 5 {
+				t.Fatalf("expected 5 records, got %d", len(lines))
+			}
+			var rec jsonRecord
+			if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Station != "dra" || rec.Timestamp.Hour() != 1 {
+				t.Errorf("unexpected record: %+v", rec)
+			}
+		}},
+		{"local time", []string{"convert", "-to", "csv", "-columns", "temp", "-time", "local", "-end", "2024-02-17T00:02"}, func(t *testing.T, out string) {
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if len(lines) != 3 || !strings.HasPrefix(lines[1], "dra,2024-02-16T16:00:00-08:00,") {
+				t.Errorf("unexpected output:\n%s", out)
+			}
+		}},
+		{"clear-sky only", []string{"convert", "-to", "csv", "-clear-sky"}, func(t *testing.T, out string) {
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if len(lines) >= 1441 {
+				t.Errorf("expected the overcast test day to be mostly filtered, got %d lines", len(lines))
+			}
+		}},
+		{"json", []string{"convert", "-to", "json"}, func(t *testing.T, out string) {
+			var stations []surfrad.Station
+			if err := json.Unmarshal([]byte(out), &stations); err != nil {
+				t.Fatal(err)
+			}
+			if len(stations) != 1 || stations[0].Len() != 1440 {
+				t.Errorf("unexpected json output")
+			}
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, errOut, code := runCLI(t, string(data), tc.args...)
+			if code != 0 {
+				t.Fatalf("exit code %d: %s", code, errOut)
+			}
+			tc.check(t, out)
+		})
+	}
+}
+
+func TestConvertSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.db")
+	if _, errOut, code := runCLI(t, "", "convert", "-to", "sqlite", "-o", path, testFile); code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, code := runCLI(t, "", "convert", "-to", "sqlite", testFile); code == 0 {
+		t.Error("sqlite output to stdout should fail")
+	}
+}
+
+func TestConvertMerge(t *testing.T) {
+	out, errOut, code := runCLI(t, "", "convert", "-merge", "last", "-columns", "temp", testFile, testFile)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 1441 {
+		t.Errorf("expected duplicates to be merged away, got %d lines", len(lines))
+	}
+	if !strings.Contains(errOut, "1440 duplicate records") {
+		t.Errorf("expected a duplicate warning, got %q", errOut)
+	}
+
+	if _, _, code = runCLI(t, "", "convert", "-merge", "error", testFile, testFile); code == 0 {
+		t.Error("merging duplicates with the error policy should fail")
+	}
+}
+
+func TestConvertFill(t *testing.T) {
+	out, errOut, code := runCLI(t, "", "convert", "-fill", "15m", "-columns", "temp", testFile)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 1441 {
+		t.Errorf("expected a complete day, got %d lines", len(lines))
+	}
+	if !strings.Contains(errOut, "Desert Rock: filled") || !strings.Contains(errOut, "inserted 0 records") {
+		t.Errorf("expected a fill summary, got %q", errOut)
+	}
+}
+
+func TestConvertUnits(t *testing.T) {
+	out, errOut, code := runCLI(t, "", "convert", "-units", "temp=degF", "-columns", "temp,rh", testFile)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if lines[0] != "station,timestamp,temp (degF),rh" {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+
+	plain, _, _ := runCLI(t, "", "convert", "-columns", "temp", testFile)
+	celsius, _ := strconv.ParseFloat(strings.Split(strings.Split(plain, "\n")[1], ",")[2], 64)
+	fahrenheit, _ := strconv.ParseFloat(strings.Split(lines[1], ",")[2], 64)
+	if math.Abs(fahrenheit-(celsius*9/5+32)) > 1e-9 {
+		t.Errorf("%v degC converted to %v degF", celsius, fahrenheit)
+	}
+}
+
+func TestStats(t *testing.T) {
+	out, errOut, code := runCLI(t, "", "stats", "-columns", "temp,dw_solar", "-day", "-p", "10,90", testFile)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	for _, want := range []string{"daytime only", "p10", "p90", "temp", "dw_solar"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("stats output missing %q:\n%s", want, out)
+		}
+	}
+
+	out, errOut, code = runCLI(t, "", "stats", "-json", "-good", testFile)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	var summaries []stats.Summary
+	if err := json.Unmarshal([]byte(out), &summaries); err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 1 || !summaries[0].GoodQC || len(summaries[0].Variables) != 20 || summaries[0].Variables[15].Count == 0 {
+		t.Errorf("unexpected summaries: %+v", summaries)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	raw, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(t.TempDir(), "tbl24048.dat")
+	if err = os.WriteFile(other, bytes.Replace(raw, []byte("Desert Rock"), []byte("Table Mountain"), 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, errOut, code := runCLI(t, "", "compare", "-columns", "temp", "-lag", "5m", testFile, other)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	for _, want := range []string{"1440 common records", "Desert Rock", "Table Mountain", "1.000", "0s"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("compare output missing %q:\n%s", want, out)
+		}
+	}
+
+	out, errOut, code = runCLI(t, "", "compare", "-json", testFile, other)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	var c stats.Comparison
+	if err = json.Unmarshal([]byte(out), &c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Stations) != 2 || len(c.Pairs) != 20 {
+		t.Errorf("unexpected comparison: %+v", c)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	out, errOut, code := runCLI(t, "", "variables")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	if !strings.Contains(out, "dw_solar") || !strings.Contains(out, "W m-2") || !strings.Contains(out, "station pressure") {
+		t.Errorf("unexpected variables output:\n%s", out)
+	}
+
+	out, errOut, code = runCLI(t, "", "variables", "-json")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	var vars []surfrad.Variable
+	if err := json.Unmarshal([]byte(out), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if len(vars) != len(surfrad.Variables) || vars[16].Unit != "degC" || vars[16].Column != 38 {
+		t.Errorf("unexpected registry: %+v", vars)
+	}
+}
+
+func TestGaps(t *testing.T) {
+	out, errOut, code := runCLI(t, "", "gaps", "-columns", "dw_solar,temp", testFile)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	for _, want := range []string{"records: 1440 of 1440 expected (100.0%)", "gaps: 0", "2024-02-17", "dw_solar"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("gaps output missing %q:\n%s", want, out)
+		}
+	}
+
+	out, errOut, code = runCLI(t, "", "gaps", "-json", "-local", testFile)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	var reports []surfrad.Completeness
+	if err := json.Unmarshal([]byte(out), &reports); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || len(reports[0].Daily) != 2 || len(reports[0].Variables) != 20 {
+		t.Errorf("unexpected report: %+v", reports)
+	}
+}
+
+func TestClimatology(t *testing.T) {
+	for _, name := range []string{"clim.json", "clim.gob"} {
+		path := filepath.Join(t.TempDir(), name)
+		_, errOut, code := runCLI(t, "", "climatology", "-o", path, "-columns", "temp,dw_solar", "-local", testFile)
+		if code != 0 {
+			t.Fatalf("exit code %d: %s", code, errOut)
+		}
+		if !strings.Contains(errOut, "Desert Rock: 2 days") {
+			t.Errorf("unexpected summary: %s", errOut)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := stats.DecodeClimatology(f, stats.EncodingForPath(path))
+		_ = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !c.LocalTime || len(c.Variables) != 2 || c.Variables[0].Monthly[1][12].N != 1 {
+			t.Errorf("unexpected climatology: %d variables", len(c.Variables))
+		}
+
+		// a single day gives one value per band, too few to score against
+		out, errOut, code := runCLI(t, "", "anomalies", "-c", path, testFile)
+		if code != 0 {
+			t.Fatalf("exit code %d: %s", code, errOut)
+		}
+		if !strings.Contains(out, "month mean") || strings.Count(out, "\n") != 1 {
+			t.Errorf("unexpected anomalies:\n%s", out)
+		}
+	}
+}
+
+func TestCat(t *testing.T) {
+	out, errOut, code := runCLI(t, "", "cat", "-columns", "temp", "-start", "2024-02-17T12:00", "-end", "2024-02-17T12:02", filepath.Join("../../testdata", "*.dat"))
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, errOut)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got:\n%s", out)
+	}
+	if !strings.Contains(lines[1], "8.3") {
+		t.Errorf("unexpected row: %s", lines[1])
+	}
+}
+
+func TestBadInvocations(t *testing.T) {
+	cases := [][]string{
+		{},
+		{"nope"},
+		{"cat", "-columns", "bogus", testFile},
+		{"info", "does-not-exist.dat"},
+		{"convert", "-to", "xml", testFile},
+		{"convert", "-time", "mars", testFile},
+		{"convert", "-merge", "newest", testFile},
+		{"convert", "-fill", "soon", testFile},
+		{"convert", "-units", "temp=inHg", testFile},
+		{"convert", "-to", "json", "-units", "temp=degF", testFile},
+		{"convert", "-to", "jsonl", "-units", "temp=degF", testFile},
+		{"convert", "-to", "sqlite", "-o", filepath.Join(os.TempDir(), "units.db"), "-units", "temp=degF", testFile},
+		{"stats", "-p", "half", testFile},
+		{"compare", testFile},
+		{"stats", "-p", "101", testFile},
+		{"gaps", "-columns", "bogus", testFile},
+		{"serve", "-max-span", "0"},
+		{"climatology", testFile},
+		{"climatology", "-o", filepath.Join(os.TempDir(), "clim.json"), "-p", "-5", testFile},
+		{"anomalies", testFile},
+		{"anomalies", "-c", "does-not-exist.json", testFile},
+	}
+	for _, args := range cases {
+		if _, _, code := runCLI(t, "", args...); code == 0 {
+			t.Errorf("run(%v) succeeded, expected failure", args)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+func runServe(args []string, std stdio) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(std.err)
+	addr := fs.String("addr", ":8080", "listen address")
+	dir := fs.String("dir", ".", "directory tree containing .dat files")
+	rescan := fs.Duration("rescan", 0, "rescan the directory at this interval (0 disables)")
+	maxSpan := fs.Duration("max-span", defaultMaxSpan, "longest time range one data request may cover")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *maxSpan <= 0 {
+		return fmt.Errorf("invalid max span %s", *maxSpan)
+	}
+
+	idx, err := newFileIndex(*dir)
+	if err != nil {
+		return err
+	}
+
+	if *rescan > 0 {
+		go func() {
+			for range time.Tick(*rescan) {
+				if err := idx.scan(); err != nil {
+					_, _ = fmt.Fprintf(std.err, "rescan: %v\n", err)
+				}
+			}
+		}()
+	}
+
+	_, _ = fmt.Fprintf(std.err, "serving %d stations from %s on %s\n", len(idx.stations()), *dir, *addr)
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           newServer(idx, *maxSpan),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return srv.ListenAndServe()
+}
+
+// defaultMaxSpan bounds data requests, since every day in the range is parsed and held in memory.
+const defaultMaxSpan = 31 * 24 * time.Hour
+
+type server struct {
+	idx     *fileIndex
+	maxSpan time.Duration
+}
+
+func newServer(idx *fileIndex, maxSpan time.Duration) http.Handler {
+	return &server{idx: idx, maxSpan: maxSpan}
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, apiError{Error: err.Error()})
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "stations":
+		s.handleStations(w)
+	case len(parts) == 2 && parts[0] == "stations":
+		s.handleStation(w, parts[1])
+	case len(parts) == 3 && parts[0] == "stations" && parts[2] == "data":
+		s.handleData(w, r, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such endpoint: %s", r.URL.Path))
+	}
+}
+
+type stationInfo struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Location  surfrad.Location `json:"location"`
+	Version   int              `json:"version"`
+	Days      int              `json:"days"`
+	FirstDay  string           `json:"first_day,omitempty"`
+	LastDay   string           `json:"last_day,omitempty"`
+	Available []string         `json:"available,omitempty"`
+}
+
+func (s *server) info(sid surfrad.StationID, withDays bool) stationInfo {
+	name, _ := surfrad.GetStationName(sid)
+	info := stationInfo{ID: sid.String(), Name: name.String()}
+
+	if hdr, ok := s.idx.header(sid); ok {
+		info.Location = hdr.LocatedAt
+		info.Version = hdr.Version
+	}
+
+	days := s.idx.coverage(sid)
+	info.Days = len(days)
+	if len(days) > 0 {
+		info.FirstDay = days[0].Format(time.DateOnly)
+		info.LastDay = days[len(days)-1].Format(time.DateOnly)
+	}
+	if withDays {
+		for _, day := range days {
+			info.Available = append(info.Available, day.Format(time.DateOnly))
+		}
+	}
+
+	return info
+}
+
+func (s *server) lookup(w http.ResponseWriter, id string) (surfrad.StationID, bool) {
+	var sid surfrad.StationID
+	if len([]rune(id)) == 3 {
+		copy(sid[:], []rune(strings.ToLower(id)))
+	}
+	if !sid.Valid() || len(s.idx.coverage(sid)) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown station: %s", id))
+		return sid, false
+	}
+	return sid, true
+}
+
+func (s *server) handleStations(w http.ResponseWriter) {
+	ids := s.idx.stations()
+	infos := make([]stationInfo, 0, len(ids))
+	for _, sid := range ids {
+		infos = append(infos, s.info(sid, false))
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *server) handleStation(w http.ResponseWriter, id string) {
+	sid, ok := s.lookup(w, id)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.info(sid, true))
+}
+
+// dataRange resolves the start/end query parameters. With neither set, the most recent day is returned;
+// with only one set, the day from start or up to end. Ranges longer than the server's maximum span are
+// rejected.
+func (s *server) dataRange(sid surfrad.StationID, q map[string][]string) (time.Time, time.Time, error) {
+	get := func(k string) string {
+		if v := q[k]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	start, err := parseTime(get("start"))
+	if err != nil {
+		return start, start, err
+	}
+	end, err := parseTime(get("end"))
+	if err != nil {
+		return start, end, err
+	}
+
+	days := s.idx.coverage(sid)
+	last := days[len(days)-1].AddDate(0, 0, 1)
+
+	switch {
+	case start.IsZero() && end.IsZero():
+		start, end = days[len(days)-1], last
+	case start.IsZero():
+		start = end.AddDate(0, 0, -1)
+	case end.IsZero():
+		end = start.AddDate(0, 0, 1)
+	}
+
+	if end.Before(start) {
+		return start, end, surfrad.ErrInvalidRange
+	}
+	if end.Sub(start) > s.maxSpan {
+		return start, end, fmt.Errorf("range of %s exceeds the maximum of %s", end.Sub(start), s.maxSpan)
+	}
+
+	return start, end, nil
+}
+
+type dataRow struct {
+	Timestamp time.Time
+	Values    []float64 // NaN where missing
+}
+
+// wallBin truncates t to width on its own wall clock, so bins start at midnight in the zone of the
+// timestamps. Solar time zone offsets drift through the year, so bins are keyed by wall clock seconds.
+func wallBin(t time.Time, width time.Duration) (time.Time, int64) {
+	bin := surfrad.TruncateIn(t, width, t.Location())
+	_, offset := bin.Zone()
+	return bin, bin.Unix() + int64(offset)
+}
+
+// resample averages the non-missing values of each column into fixed width bins aligned to midnight
+// in the rows' time zone.
+func resample(rows []dataRow, width time.Duration) []dataRow {
+	if width <= 0 || len(rows) == 0 {
+		return rows
+	}
+
+	var (
+		out    []dataRow
+		sums   []float64
+		counts []int
+	)
+
+	flush := func(ts time.Time) {
+		row := dataRow{Timestamp: ts, Values: make([]float64, len(sums))}
+		for i := range sums {
+			row.Values[i] = math.NaN()
+			if counts[i] > 0 {
+				row.Values[i] = sums[i] / float64(counts[i])
+			}
+		}
+		out = append(out, row)
+	}
+
+	bin, key := wallBin(rows[0].Timestamp, width)
+	sums = make([]float64, len(rows[0].Values))
+	counts = make([]int, len(rows[0].Values))
+
+	for _, r := range rows {
+		if b, k := wallBin(r.Timestamp, width); k != key {
+			flush(bin)
+			bin, key = b, k
+			sums = make([]float64, len(r.Values))
+			counts = make([]int, len(r.Values))
+		}
+		for i, v := range r.Values {
+			if !math.IsNaN(v) {
+				sums[i] += v
+				counts[i]++
+			}
+		}
+	}
+	flush(bin)
+
+	return out
+}
+
+func (s *server) handleData(w http.ResponseWriter, r *http.Request, id string) {
+	sid, ok := s.lookup(w, id)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+
+	start, end, err := s.dataRange(sid, q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cols, err := selectColumns(q.Get("fields"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var width time.Duration
+	if res := q.Get("resample"); res != "" {
+		if width, err = time.ParseDuration(res); err != nil || width < time.Minute {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("bad resample interval %q: want a duration of at least 1m", res))
+			return
+		}
+	}
+
+	clk, err := parseClock(q.Get("time"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	st, err := s.idx.load(sid, start, end)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	rows := make([]dataRow, 0, st.Len())
+	for i := range st.Entries {
+		d := &st.Entries[i]
+		row := dataRow{Timestamp: clk(st, d.Timestamp), Values: make([]float64, len(cols))}
+		for j, c := range cols {
+			row.Values[j] = c.Value(d)
+			if c.Missing(d) {
+				row.Values[j] = math.NaN()
+			}
+		}
+		rows = append(rows, row)
+	}
+	rows = resample(rows, width)
+
+	switch format := q.Get("format"); format {
+	case "", "json":
+		writeDataJSON(w, sid, start, end, cols, rows)
+	case "csv":
+		writeDataCSV(w, cols, rows)
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown format %q, expected json or csv", format))
+	}
+}
+
+func writeDataJSON(w http.ResponseWriter, sid surfrad.StationID, start, end time.Time, cols []surfrad.Variable, rows []dataRow) {
+	type response struct {
+		Station string           `json:"station"`
+		Start   time.Time        `json:"start"`
+		End     time.Time        `json:"end"`
+		Fields  []string         `json:"fields"`
+		Units   []string         `json:"units"`
+		Records []map[string]any `json:"records"`
+	}
+
+	resp := response{Station: sid.String(), Start: start, End: end, Records: make([]map[string]any, 0, len(rows))}
+	for _, c := range cols {
+		resp.Fields = append(resp.Fields, c.Name)
+		resp.Units = append(resp.Units, c.Unit)
+	}
+
+	for _, row := range rows {
+		rec := map[string]any{"timestamp": row.Timestamp}
+		for i, c := range cols {
+			if math.IsNaN(row.Values[i]) {
+				rec[c.Name] = nil
+			} else {
+				rec[c.Name] = row.Values[i]
+			}
+		}
+		resp.Records = append(resp.Records, rec)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeDataCSV(w http.ResponseWriter, cols []surfrad.Variable, rows []dataRow) {
+	w.Header().Set("Content-Type", "text/csv")
+	cw := csv.NewWriter(w)
+
+	header := []string{"timestamp"}
+	for _, c := range cols {
+		header = append(header, c.Name)
+	}
+	_ = cw.Write(header)
+
+	for _, row := range rows {
+		record := []string{row.Timestamp.Format(time.RFC3339)}
+		for _, v := range row.Values {
+			if math.IsNaN(v) {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		_ = cw.Write(record)
+	}
+
+	cw.Flush()
+}
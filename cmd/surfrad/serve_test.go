@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	idx, err := newFileIndex("../../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(newServer(idx, defaultMaxSpan))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func getBody(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestParseFileName(t *testing.T) {
+	cases := []struct {
+		name string
+		day  time.Time
+		ok   bool
+	}{
+		{"dra24048.dat", time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC), true},
+		{"tbl95001.dat", time.Date(1995, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{"xyz24048.dat", time.Time{}, false},
+		{"dra24048.csv", time.Time{}, false},
+		{"dra24400.dat", time.Time{}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, day, ok := parseFileName(tc.name)
+			if ok != tc.ok || (ok && !day.Equal(tc.day)) {
+				t.Errorf("parseFileName(%q) == %v, %t, expected %v, %t", tc.name, day, ok, tc.day, tc.ok)
+			}
+		})
+	}
+}
+
+func TestServeStations(t *testing.T) {
+	srv := newTestServer(t)
+
+	code, body := getBody(t, srv.URL+"/stations")
+	if code != http.StatusOK {
+		t.Fatalf("status %d: %s", code, body)
+	}
+	var infos []stationInfo
+	if err := json.Unmarshal([]byte(body), &infos); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].ID != "dra" || infos[0].Days != 1 || infos[0].Location.Elevation != 1007 {
+		t.Errorf("unexpected stations: %+v", infos)
+	}
+
+	code, body = getBody(t, srv.URL+"/stations/dra")
+	if code != http.StatusOK || !strings.Contains(body, `"available":["2024-02-17"]`) {
+		t.Errorf("status %d: %s", code, body)
+	}
+
+	if code, _ = getBody(t, srv.URL+"/stations/bon"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a station without data, got %d", code)
+	}
+}
+
+func TestServeData(t *testing.T) {
+	srv := newTestServer(t)
+
+	cases := []struct {
+		name    string
+		query   string
+		status  int
+		records int
+	}{
+		{"latest day", "", http.StatusOK, 1440},
+		{"range", "?start=2024-02-17T12:00&end=2024-02-17T13:00&fields=temp,rh", http.StatusOK, 60},
+		{"resampled", "?start=2024-02-17&end=2024-02-18&resample=1h&fields=temp", http.StatusOK, 24},
+		{"local days", "?start=2024-02-17&end=2024-02-18&resample=24h&time=local&fields=temp", http.StatusOK, 2},
+		{"solar hours", "?start=2024-02-17&end=2024-02-18&resample=1h&time=solar&fields=temp", http.StatusOK, 25},
+		{"bad time", "?time=mars", http.StatusBadRequest, 0},
+		{"outside coverage", "?start=2020-01-01&end=2020-01-02", http.StatusOK, 0},
+		{"bad field", "?fields=nope", http.StatusBadRequest, 0},
+		{"bad resample", "?resample=5s", http.StatusBadRequest, 0},
+		{"inverted range", "?start=2024-02-18&end=2024-02-17", http.StatusBadRequest, 0},
+		{"span too long", "?start=2020-01-01&end=2024-02-18", http.StatusBadRequest, 0},
+		{"open end", "?start=2024-02-17T23:00", http.StatusOK, 60},
+		{"open start", "?end=2024-02-17T01:00", http.StatusOK, 60},
+		{"open end before a long archive", "?start=2024-01-01", http.StatusOK, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, body := getBody(t, srv.URL+"/stations/dra/data"+tc.query)
+			if code != tc.status {
+				t.Fatalf("status %d, expected %d: %s", code, tc.status, body)
+			}
+			if code != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Fields  []string         `json:"fields"`
+				Units   []string         `json:"units"`
+				Records []map[string]any `json:"records"`
+			}
+			if err := json.Unmarshal([]byte(body), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Records) != tc.records {
+				t.Errorf("got %d records, expected %d", len(resp.Records), tc.records)
+			}
+			if len(resp.Units) != len(resp.Fields) || (resp.Fields[0] == "temp" && resp.Units[0] != "degC") {
+				t.Errorf("units %v don't match fields %v", resp.Units, resp.Fields)
+			}
+		})
+	}
+}
+
+func TestServeDataCSV(t *testing.T) {
+	srv := newTestServer(t)
+
+	code, body := getBody(t, srv.URL+"/stations/dra/data?start=2024-02-17T12:00&end=2024-02-17T12:02&fields=temp&format=csv")
+	if code != http.StatusOK {
+		t.Fatalf("status %d: %s", code, body)
+	}
+	expected := "timestamp,temp\n2024-02-17T12:00:00Z,8.3\n2024-02-17T12:01:00Z,8.2\n"
+	if body != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", body, expected)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+
+	"git.tcp.direct/kayos/surfrad"
+	"git.tcp.direct/kayos/surfrad/stats"
+)
+
+func runStats(args []string, std stdio) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.SetOutput(std.err)
+	vars := fs.String("columns", "", "comma separated variables to summarize, default all measured: "+columnNames())
+	daytime := fs.Bool("day", false, "only records with the sun above the horizon")
+	good := fs.Bool("good", false, "only values flagged good")
+	pcts := fs.String("p", "5,25,75,95", "comma separated percentiles to report")
+	asJSON := fs.Bool("json", false, "write the summary as JSON")
+	strict := fs.Bool("strict", false, "fail on any parse error instead of warning")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := stats.DefaultOptions()
+	opts.Daytime, opts.GoodQC = *daytime, *good
+	if strings.TrimSpace(*vars) != "" {
+		for _, name := range strings.Split(*vars, ",") {
+			opts.Variables = append(opts.Variables, strings.TrimSpace(name))
+		}
+	}
+	pct, err := parsePercentiles(*pcts)
+	if err != nil {
+		return err
+	}
+	opts.Percentiles = pct
+
+	inputs, err := readInputs(fs.Args(), std, *strict)
+	if err != nil {
+		return err
+	}
+
+	stations := make([]surfrad.Station, len(inputs))
+	for i, in := range inputs {
+		stations[i] = in.station
+	}
+	if stations, err = mergeStations(stations, surfrad.KeepFirst, std); err != nil {
+		return err
+	}
+
+	summaries := make([]stats.Summary, 0, len(stations))
+	for _, st := range stations {
+		sum, err := stats.Summarize(st, opts)
+		if err != nil {
+			return err
+		}
+		summaries = append(summaries, sum)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(std.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summaries)
+	}
+
+	for i, sum := range summaries {
+		if i > 0 {
+			_, _ = fmt.Fprintln(std.out)
+		}
+		if err = sum.WriteText(std.out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+func runVariables(args []string, std stdio) error {
+	fs := flag.NewFlagSet("variables", flag.ContinueOnError)
+	fs.SetOutput(std.err)
+	asJSON := fs.Bool("json", false, "write the registry as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(std.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(surfrad.Variables)
+	}
+
+	tw := tabwriter.NewWriter(std.out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "name\tunit\tcolumn\tqc\trange\tdescription\tconverts to")
+	for _, v := range surfrad.Variables {
+		qc := "-"
+		if v.QCColumn >= 0 {
+			qc = strconv.Itoa(v.QCColumn)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%g to %g\t%s\t%s\n", v.Name, v.Unit, v.Column, qc, v.Min, v.Max, v.Description,
+			strings.Join(surfrad.ConvertibleUnits(v)[1:], ", "))
+	}
+	return tw.Flush()
+}
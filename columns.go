@@ -0,0 +1,175 @@
+package surfrad
+
+import (
+	"math"
+	"time"
+)
+
+// Columns holds a station's entries column by column: one slice of timestamps, and a slice of values and
+// of QC flags per entry of Variables, all of the same length. It suits numeric work that looks at one
+// variable across many records.
+type Columns struct {
+	StationName StationName
+	LocatedAt   Location
+	Version     int
+
+	Time []time.Time
+	Raw  []RawEntryTime
+
+	values [][]float64 // indexed like Variables
+	flags  [][]int     // indexed like Variables, nil for variables without a flag
+}
+
+// Columns converts the entries to columns.
+func (s Station) Columns() Columns {
+	n := len(s.Entries)
+	c := Columns{
+		StationName: s.StationName,
+		LocatedAt:   s.LocatedAt,
+		Version:     s.Version,
+		Time:        make([]time.Time, n),
+		Raw:         make([]RawEntryTime, n),
+		values:      make([][]float64, len(Variables)),
+		flags:       make([][]int, len(Variables)),
+	}
+	for j, v := range Variables {
+		c.values[j] = make([]float64, n)
+		if v.QC != nil {
+			c.flags[j] = make([]int, n)
+		}
+	}
+
+	for i := range s.Entries {
+		d := &s.Entries[i]
+		c.Time[i], c.Raw[i] = d.Timestamp, d.RawTimestamp
+		for j, v := range Variables {
+			c.values[j][i] = v.Value(d)
+			if v.QC != nil {
+				c.flags[j][i] = v.QC(d)
+			}
+		}
+	}
+	return c
+}
+
+// column returns the values and flags of entry j of Variables, each nil if c doesn't hold it with one per
+// record, as when c was built as a literal rather than by Station.Columns.
+func (c Columns) column(j int) ([]float64, []int) {
+	var (
+		values []float64
+		flags  []int
+	)
+	if j < len(c.values) && len(c.values[j]) == c.Len() {
+		values = c.values[j]
+	}
+	if j < len(c.flags) && len(c.flags[j]) == c.Len() {
+		flags = c.flags[j]
+	}
+	return values, flags
+}
+
+// Station converts the columns back to entries. Variables the columns don't hold are missing.
+func (c Columns) Station() Station {
+	s := Station{
+		StationName: c.StationName,
+		LocatedAt:   c.LocatedAt,
+		Version:     c.Version,
+		Entries:     make([]Data, c.Len()),
+	}
+	for i := range s.Entries {
+		d := &s.Entries[i]
+		d.Timestamp = c.Time[i]
+		if len(c.Raw) == c.Len() {
+			d.RawTimestamp = c.Raw[i]
+		} else {
+			d.RawTimestamp = rawEntryTime(c.Time[i])
+		}
+		for j, v := range Variables {
+			values, flags := c.column(j)
+			if values == nil {
+				v.Set(d, 0, QCMissing)
+				continue
+			}
+			qc := QCGood
+			if flags != nil {
+				qc = flags[i]
+			}
+			v.Set(d, values[i], qc)
+		}
+	}
+	return s
+}
+
+func (c Columns) Len() int {
+	return len(c.Time)
+}
+
+// Values returns the named variable's column as stored, with missing values as in Data. The slice is
+// shared with c, so changes to it change c.
+func (c Columns) Values(name string) ([]float64, bool) {
+	j := variableIndex(name)
+	if j < 0 {
+		return nil, false
+	}
+	values, _ := c.column(j)
+	return values, values != nil
+}
+
+// Flags returns the named variable's QC flags, shared with c. It's false for unknown variables and for
+// those without a flag.
+func (c Columns) Flags(name string) ([]int, bool) {
+	j := variableIndex(name)
+	if j < 0 {
+		return nil, false
+	}
+	_, flags := c.column(j)
+	return flags, flags != nil
+}
+
+// Masked returns a copy of the named variable's column with missing values, and optionally those
+// flagged questionable, replaced by NaN.
+func (c Columns) Masked(name string, dropQuestionable bool) ([]float64, bool) {
+	j := variableIndex(name)
+	if j < 0 {
+		return nil, false
+	}
+	values, flags := c.column(j)
+	if values == nil {
+		return nil, false
+	}
+	out := make([]float64, c.Len())
+	for i, value := range values {
+		qc := QCGood
+		if flags != nil {
+			qc = flags[i]
+		}
+		if IsMissing(value, qc) || (dropQuestionable && qc == QCQuestionable) {
+			value = math.NaN()
+		}
+		out[i] = value
+	}
+	return out, true
+}
+
+// Slice returns the columns of records i through j-1, sharing storage with c.
+func (c Columns) Slice(i, j int) Columns {
+	out := c
+	out.Time = c.Time[i:j]
+	if len(c.Raw) == c.Len() {
+		out.Raw = c.Raw[i:j]
+	} else {
+		out.Raw = nil
+	}
+	out.values = make([][]float64, len(c.values))
+	out.flags = make([][]int, len(c.flags))
+	for k := range c.values {
+		values, flags := c.column(k)
+		if values != nil {
+			out.values[k] = values[i:j]
+		}
+		if flags != nil {
+			out.flags[k] = flags[i:j]
+		}
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package surfrad
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestColumns(t *testing.T) {
+	st := readTestStation(t)
+	st.Entries[10].TemperatureC, st.Entries[10].QC.TemperatureC = 0, QCMissing
+	st.Entries[11].QC.TemperatureC = QCQuestionable
+
+	c := st.Columns()
+	if c.Len() != st.Len() || c.StationName != st.StationName || c.LocatedAt != st.LocatedAt {
+		t.Fatalf("unexpected columns header %v %v, %d records", c.StationName, c.LocatedAt, c.Len())
+	}
+	if back := c.Station(); !reflect.DeepEqual(back, st) {
+		t.Error("round trip through columns changed the station")
+	}
+
+	temp, ok := c.Values("temp")
+	if !ok || temp[12] != st.Entries[12].TemperatureC {
+		t.Fatalf("Values(temp) == %v, %t", temp[12], ok)
+	}
+	flags, ok := c.Flags("temp")
+	if !ok || flags[10] != QCMissing || flags[11] != QCQuestionable {
+		t.Errorf("Flags(temp) == %v, %t", flags[10:12], ok)
+	}
+	if _, ok = c.Flags("zen"); ok {
+		t.Error("the zenith angle has no flags")
+	}
+	if _, ok = c.Values("bogus"); ok {
+		t.Error("expected no column for an unknown variable")
+	}
+
+	masked, _ := c.Masked("temp", false)
+	if !math.IsNaN(masked[10]) || math.IsNaN(masked[11]) || masked[12] != temp[12] {
+		t.Errorf("unexpected masked values %v", masked[10:13])
+	}
+	masked, _ = c.Masked("temp", true)
+	if !math.IsNaN(masked[11]) {
+		t.Error("questionable values should be masked")
+	}
+
+	temp[12] = 99
+	if c.Station().Entries[12].TemperatureC != 99 {
+		t.Error("Values should share storage with the columns")
+	}
+
+	hour := c.Slice(60, 120)
+	if hour.Len() != 60 || !hour.Time[0].Equal(st.Entries[60].Timestamp) {
+		t.Fatalf("unexpected slice of %d from %v", hour.Len(), hour.Time[0])
+	}
+	if dw, _ := hour.Values("dw_solar"); dw[0] != st.Entries[60].DownwellingSolar {
+		t.Errorf("slice dw_solar %v, expected %v", dw[0], st.Entries[60].DownwellingSolar)
+	}
+
+	if empty := (Station{}).Columns().Station(); empty.Len() != 0 {
+		t.Error("expected an empty station")
+	}
+}
+
+func TestColumnsLiteral(t *testing.T) {
+	st := readTestStation(t)
+	c := Columns{StationName: st.StationName, Time: []time.Time{st.Entries[0].Timestamp, st.Entries[1].Timestamp}}
+
+	back := c.Station()
+	if back.Len() != 2 || !back.Entries[1].Timestamp.Equal(st.Entries[1].Timestamp) {
+		t.Fatalf("unexpected station %+v", back.Entries)
+	}
+	temp, _ := LookupVariable("temp")
+	if !temp.Missing(&back.Entries[0]) {
+		t.Error("variables the columns don't hold should be missing")
+	}
+	if _, ok := c.Values("temp"); ok {
+		t.Error("expected no values in a literal")
+	}
+	if _, ok := c.Flags("temp"); ok {
+		t.Error("expected no flags in a literal")
+	}
+	if _, ok := c.Masked("temp", false); ok {
+		t.Error("expected nothing to mask in a literal")
+	}
+	if one := c.Slice(1, 2); one.Len() != 1 || one.Station().Len() != 1 {
+		t.Error("unexpected slice of a literal")
+	}
+}
@@ -0,0 +1,114 @@
+package surfrad
+
+import "math"
+
+// Derived meteorological quantities. Each of these returns MissingValue when any of its inputs
+// is missing or physically meaningless (e.g. zero relative humidity or pressure).
+
+const (
+	gasConstantDryAir    = 287.05 // J kg^-1 K^-1
+	gasConstantWater     = 461.5  // J kg^-1 K^-1
+	epsilon              = gasConstantDryAir / gasConstantWater
+	kappa                = 0.2857 // R/cp for dry air
+	referencePressure    = 1000.0 // hPa
+	celsiusToKelvin      = 273.15
+	magnusA              = 6.112 // hPa
+	magnusB              = 17.67
+	magnusC              = 243.5 // °C
+	minPrecipitableWater = 0.1   // cm
+)
+
+func (d Data) temperatureMissing() bool {
+	return IsMissing(d.TemperatureC, d.QC.TemperatureC)
+}
+
+func (d Data) humidityMissing() bool {
+	return IsMissing(d.RelativeHumidity, d.QC.RelativeHumidity) || d.RelativeHumidity <= 0
+}
+
+func (d Data) pressureMissing() bool {
+	return IsMissing(d.BarometricPressure, d.QC.BarometricPressure) || d.BarometricPressure <= 0
+}
+
+// TemperatureK returns the air temperature in Kelvin.
+func (d Data) TemperatureK() float64 {
+	if d.temperatureMissing() {
+		return MissingValue
+	}
+	return d.TemperatureC + celsiusToKelvin
+}
+
+// SaturationVaporPressure returns the saturation vapor pressure over water in hPa (Bolton 1980).
+func (d Data) SaturationVaporPressure() float64 {
+	if d.temperatureMissing() {
+		return MissingValue
+	}
+	return magnusA * math.Exp(magnusB*d.TemperatureC/(d.TemperatureC+magnusC))
+}
+
+// VaporPressure returns the actual vapor pressure in hPa.
+func (d Data) VaporPressure() float64 {
+	if d.temperatureMissing() || d.humidityMissing() {
+		return MissingValue
+	}
+	return d.RelativeHumidity / 100 * d.SaturationVaporPressure()
+}
+
+// DewPoint returns the dew point temperature in °C.
+func (d Data) DewPoint() float64 {
+	e := d.VaporPressure()
+	if e == MissingValue {
+		return MissingValue
+	}
+	x := math.Log(e / magnusA)
+	return magnusC * x / (magnusB - x)
+}
+
+// SpecificHumidity returns the mass of water vapor per mass of moist air (kg/kg).
+func (d Data) SpecificHumidity() float64 {
+	e := d.VaporPressure()
+	if e == MissingValue || d.pressureMissing() {
+		return MissingValue
+	}
+	return epsilon * e / (d.BarometricPressure - (1-epsilon)*e)
+}
+
+// MixingRatio returns the mass of water vapor per mass of dry air (kg/kg).
+func (d Data) MixingRatio() float64 {
+	e := d.VaporPressure()
+	if e == MissingValue || d.pressureMissing() {
+		return MissingValue
+	}
+	return epsilon * e / (d.BarometricPressure - e)
+}
+
+// AirDensity returns the density of moist air in kg m^-3, as the sum of the dry air and vapor partial densities.
+func (d Data) AirDensity() float64 {
+	e := d.VaporPressure()
+	if e == MissingValue || d.pressureMissing() {
+		return MissingValue
+	}
+	t := d.TemperatureC + celsiusToKelvin
+	return (d.BarometricPressure-e)*100/(gasConstantDryAir*t) + e*100/(gasConstantWater*t)
+}
+
+// PotentialTemperature returns the temperature in Kelvin air would have if brought adiabatically to 1000 hPa.
+func (d Data) PotentialTemperature() float64 {
+	if d.temperatureMissing() || d.pressureMissing() {
+		return MissingValue
+	}
+	return (d.TemperatureC + celsiusToKelvin) * math.Pow(referencePressure/d.BarometricPressure, kappa)
+}
+
+// PrecipitableWater estimates the precipitable water column in cm from surface temperature and humidity (Gueymard 1994).
+func (d Data) PrecipitableWater() float64 {
+	e := d.VaporPressure()
+	if e == MissingValue {
+		return MissingValue
+	}
+	t := d.TemperatureC + celsiusToKelvin
+	theta := t / celsiusToKelvin
+	scaleHeight := 0.4976 + 1.5265*theta + math.Exp(13.6897*theta-14.9188*math.Pow(theta, 3)) // km
+	vaporDensity := 216.7 * e / t                                                             // g m^-3
+	return math.Max(minPrecipitableWater, 0.1*scaleHeight*vaporDensity)
+}
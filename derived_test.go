@@ -0,0 +1,65 @@
+package surfrad
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDerivedMeteorology(t *testing.T) {
+	d := Data{TemperatureC: 20, RelativeHumidity: 50, BarometricPressure: 1013.25}
+
+	cases := []struct {
+		name     string
+		got      float64
+		expected float64
+		tol      float64
+	}{
+		{"temperature K", d.TemperatureK(), 293.15, 1e-9},
+		{"saturation vapor pressure", d.SaturationVaporPressure(), 23.37, 0.01},
+		{"vapor pressure", d.VaporPressure(), 11.69, 0.01},
+		{"dew point", d.DewPoint(), 9.26, 0.02},
+		{"specific humidity", d.SpecificHumidity(), 0.00720, 0.00002},
+		{"mixing ratio", d.MixingRatio(), 0.00725, 0.00002},
+		{"air density", d.AirDensity(), 1.198, 0.002},
+		{"potential temperature", d.PotentialTemperature(), 292.05, 0.02},
+		{"precipitable water", d.PrecipitableWater(), 1.866, 0.005},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if math.Abs(tc.got-tc.expected) > tc.tol {
+				t.Errorf("got %v, expected %v ± %v", tc.got, tc.expected, tc.tol)
+			}
+		})
+	}
+}
+
+func TestDerivedMeteorologyMissing(t *testing.T) {
+	cases := []struct {
+		name string
+		d    Data
+	}{
+		{"missing temperature", Data{RelativeHumidity: 50, BarometricPressure: 1000, QC: QCFlags{TemperatureC: QCMissing}}},
+		{"missing humidity", Data{TemperatureC: 10, BarometricPressure: 1000, QC: QCFlags{RelativeHumidity: QCMissing}}},
+		{"missing pressure", Data{TemperatureC: 10, RelativeHumidity: 50, QC: QCFlags{BarometricPressure: QCMissing}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.d.SpecificHumidity(); got != MissingValue {
+				t.Errorf("SpecificHumidity() == %v, expected MissingValue", got)
+			}
+			if got := tc.d.AirDensity(); got != MissingValue {
+				t.Errorf("AirDensity() == %v, expected MissingValue", got)
+			}
+		})
+	}
+
+	// freezing point is a real temperature, not a missing one
+	if got := (Data{TemperatureC: 0, RelativeHumidity: 100}).DewPoint(); math.Abs(got) > 1e-9 {
+		t.Errorf("DewPoint() at 0°C and saturation == %v, expected 0", got)
+	}
+	if got := (Data{TemperatureC: 5, QC: QCFlags{RelativeHumidity: QCMissing}}).DewPoint(); got != MissingValue {
+		t.Errorf("DewPoint() with missing humidity == %v, expected MissingValue", got)
+	}
+}
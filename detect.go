@@ -0,0 +1,265 @@
+package surfrad
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ClearSkyDetection configures the Reno & Hansen (2016) clear-sky detection algorithm, which compares
+// sliding windows of measured GHI with modeled clear-sky GHI on five criteria. Thresholds are those of
+// the original paper for 1-minute data.
+type ClearSkyDetection struct {
+	Model  ClearSkyModel
+	Window time.Duration
+
+	MeanDiff        float64 // max |mean(GHI) - mean(clear)| in W m^-2
+	MaxDiff         float64 // max |max(GHI) - max(clear)| in W m^-2
+	LowerLineLength float64 // min line length difference
+	UpperLineLength float64 // max line length difference
+	VarDiff         float64 // max normalized standard deviation of slopes
+	SlopeDev        float64 // max |slope(GHI) - slope(clear)| in W m^-2 per sample
+
+	// MaxIterations bounds the rescaling of the clear-sky model to fit clear periods. 1 disables rescaling.
+	MaxIterations int
+}
+
+func DefaultClearSkyDetection() ClearSkyDetection {
+	return ClearSkyDetection{
+		Model:           NewIneichen(3),
+		Window:          10 * time.Minute,
+		MeanDiff:        75,
+		MaxDiff:         75,
+		LowerLineLength: -5,
+		UpperLineLength: 10,
+		VarDiff:         0.005,
+		SlopeDev:        8,
+		MaxIterations:   20,
+	}
+}
+
+var ErrWindowTooShort = errors.New("detection window must span at least 3 samples")
+
+// Period is a contiguous span of entries, inclusive of both ends.
+type Period struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (p Period) Duration() time.Duration {
+	return p.End.Sub(p.Start)
+}
+
+type windowStats struct {
+	mean, max, lineLength, slopeNStd float64
+}
+
+func calcWindowStats(values []float64, dt float64) windowStats {
+	var ws windowStats
+	ws.max = math.Inf(-1)
+
+	var slopes []float64
+	for i, v := range values {
+		ws.mean += v
+		ws.max = math.Max(ws.max, v)
+		if i > 0 {
+			d := v - values[i-1]
+			slopes = append(slopes, d)
+			ws.lineLength += math.Sqrt(d*d + dt*dt)
+		}
+	}
+	ws.mean /= float64(len(values))
+
+	var slopeMean, slopeVar float64
+	for _, s := range slopes {
+		slopeMean += s
+	}
+	slopeMean /= float64(len(slopes))
+	for _, s := range slopes {
+		slopeVar += (s - slopeMean) * (s - slopeMean)
+	}
+	if len(slopes) > 1 {
+		slopeVar /= float64(len(slopes) - 1)
+	}
+	if ws.mean != 0 {
+		ws.slopeNStd = math.Sqrt(slopeVar) / ws.mean
+	} else {
+		ws.slopeNStd = math.Inf(1)
+	}
+
+	return ws
+}
+
+// contiguous reports whether entries[start:end] are evenly spaced at step.
+func contiguous(entries []Data, start, end int, step time.Duration) bool {
+	for i := start + 1; i < end; i++ {
+		if entries[i].Timestamp.Sub(entries[i-1].Timestamp) != step {
+			return false
+		}
+	}
+	return true
+}
+
+// clearWindows evaluates the criteria for every window start, returning which samples fall inside
+// at least one window that meets all of them.
+func (o ClearSkyDetection) clearWindows(entries []Data, ghi, clear []float64, ok []bool, n int, step time.Duration) []bool {
+	mask := make([]bool, len(entries))
+	dt := step.Minutes()
+
+	for start := 0; start+n <= len(entries); start++ {
+		end := start + n
+
+		valid := contiguous(entries, start, end, step)
+		for i := start; valid && i < end; i++ {
+			valid = ok[i]
+		}
+		if !valid {
+			continue
+		}
+
+		meas := calcWindowStats(ghi[start:end], dt)
+		cs := calcWindowStats(clear[start:end], dt)
+		if cs.mean <= 0 {
+			continue
+		}
+
+		slopeDev := 0.0
+		for i := start + 1; i < end; i++ {
+			slopeDev = math.Max(slopeDev, math.Abs((ghi[i]-ghi[i-1])-(clear[i]-clear[i-1])))
+		}
+
+		lineDiff := meas.lineLength - cs.lineLength
+
+		if math.Abs(meas.mean-cs.mean) < o.MeanDiff &&
+			math.Abs(meas.max-cs.max) < o.MaxDiff &&
+			lineDiff > o.LowerLineLength && lineDiff < o.UpperLineLength &&
+			meas.slopeNStd < o.VarDiff &&
+			slopeDev < o.SlopeDev {
+			for i := start; i < end; i++ {
+				mask[i] = true
+			}
+		}
+	}
+
+	return mask
+}
+
+// DetectClearSky returns a mask with one element per entry, true where the entry lies within a clear
+// window. Entries are assumed to be sorted; windows spanning gaps or missing GHI are never clear.
+func (s Station) DetectClearSky(opts ClearSkyDetection) ([]bool, error) {
+	def := DefaultClearSkyDetection()
+	if opts.Model == nil {
+		opts.Model = def.Model
+	}
+	if opts.Window <= 0 {
+		opts.Window = def.Window
+	}
+	if opts.MaxIterations < 1 {
+		opts.MaxIterations = 1
+	}
+
+	const step = time.Minute
+	n := int(opts.Window / step)
+	if n < 3 {
+		return nil, ErrWindowTooShort
+	}
+
+	ghi := make([]float64, len(s.Entries))
+	ok := make([]bool, len(s.Entries))
+	for i, d := range s.Entries {
+		ghi[i] = d.DownwellingSolar
+		ok[i] = !IsMissing(d.DownwellingSolar, d.QC.DownwellingSolar)
+	}
+
+	modeled := s.ClearSky(opts.Model)
+	clear := make([]float64, len(modeled))
+
+	alpha := 1.0
+	var mask []bool
+
+	for iter := 0; iter < opts.MaxIterations; iter++ {
+		for i, cs := range modeled {
+			clear[i] = alpha * cs.GHI
+		}
+
+		mask = opts.clearWindows(s.Entries, ghi, clear, ok, n, step)
+
+		// rescale the model to minimize the error over the clear samples, then try again
+		var num, den float64
+		for i, m := range mask {
+			if m {
+				num += ghi[i] * modeled[i].GHI
+				den += modeled[i].GHI * modeled[i].GHI
+			}
+		}
+		if den == 0 {
+			break
+		}
+		previous := alpha
+		alpha = num / den
+		if math.Abs(alpha-previous) < 1e-6 {
+			break
+		}
+	}
+
+	return mask, nil
+}
+
+// MaskPeriods turns a per-entry mask into contiguous periods. A period is broken by an unset entry
+// or by a gap of more than maxGap between consecutive entries.
+func (s Station) MaskPeriods(mask []bool, maxGap time.Duration) []Period {
+	var (
+		periods []Period
+		open    bool
+	)
+
+	for i, d := range s.Entries {
+		if i >= len(mask) || !mask[i] {
+			open = false
+			continue
+		}
+		if open && d.Timestamp.Sub(periods[len(periods)-1].End) <= maxGap {
+			periods[len(periods)-1].End = d.Timestamp
+			continue
+		}
+		periods = append(periods, Period{Start: d.Timestamp, End: d.Timestamp})
+		open = true
+	}
+
+	return periods
+}
+
+// ClearPeriods runs DetectClearSky and returns the contiguous clear periods.
+func (s Station) ClearPeriods(opts ClearSkyDetection) ([]Period, error) {
+	mask, err := s.DetectClearSky(opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.MaskPeriods(mask, time.Minute), nil
+}
+
+// Filter returns a copy of the station keeping only the entries for which keep returns true.
+func (s Station) Filter(keep func(i int, d Data) bool) Station {
+	entries := make([]Data, 0, len(s.Entries))
+	for i, d := range s.Entries {
+		if keep(i, d) {
+			entries = append(entries, d)
+		}
+	}
+	s.Entries = entries
+	return s
+}
+
+// Mask returns a copy of the station keeping only the entries whose mask element is true.
+func (s Station) Mask(mask []bool) Station {
+	return s.Filter(func(i int, _ Data) bool { return i < len(mask) && mask[i] })
+}
+
+// ClearSkyOnly returns a copy of the station keeping only entries detected as clear.
+func (s Station) ClearSkyOnly(opts ClearSkyDetection) (Station, error) {
+	mask, err := s.DetectClearSky(opts)
+	if err != nil {
+		return s, err
+	}
+	return s.Mask(mask), nil
+}
@@ -0,0 +1,142 @@
+package surfrad
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// syntheticDay builds a day of 1-minute entries following a clear-sky model scaled by factor,
+// with a cloudy spell between cloudStart and cloudEnd.
+func syntheticDay(model ClearSkyModel, factor float64, cloudStart, cloudEnd time.Time) Station {
+	st := Station{StationName: StationDesertRock, LocatedAt: desertRock}
+	day := time.Date(2024, 6, 21, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 1440; i++ {
+		ts := day.Add(time.Duration(i) * time.Minute)
+		ghi := factor * model.ClearSky(ts.Add(-30*time.Second), desertRock).GHI
+		if !ts.Before(cloudStart) && ts.Before(cloudEnd) {
+			ghi *= 0.3 + 0.5*math.Abs(math.Sin(float64(i)))
+		}
+		st.Entries = append(st.Entries, Data{Timestamp: ts, DownwellingSolar: ghi})
+	}
+	return st
+}
+
+func TestDetectClearSky(t *testing.T) {
+	model := NewIneichen(3)
+	cloudStart := time.Date(2024, 6, 21, 18, 0, 0, 0, time.UTC)
+	cloudEnd := time.Date(2024, 6, 21, 19, 0, 0, 0, time.UTC)
+
+	// 15% below the model, which the iterative rescaling should absorb
+	st := syntheticDay(model, 0.85, cloudStart, cloudEnd)
+
+	mask, err := st.DetectClearSky(DefaultClearSkyDetection())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mask) != st.Len() {
+		t.Fatalf("mask has %d elements, expected %d", len(mask), st.Len())
+	}
+
+	for i, d := range st.Entries {
+		zen := desertRock.SunPosition(d.Timestamp).Zenith
+		cloudy := !d.Timestamp.Before(cloudStart) && d.Timestamp.Before(cloudEnd)
+		switch {
+		case cloudy && mask[i]:
+			t.Errorf("%s: cloudy minute detected as clear", d.Timestamp)
+		case !cloudy && zen < 75 && !mask[i] && d.Timestamp.Sub(cloudEnd) > 10*time.Minute && cloudStart.Sub(d.Timestamp) > 10*time.Minute:
+			t.Errorf("%s: clear minute not detected", d.Timestamp)
+		}
+	}
+
+	periods := st.MaskPeriods(mask, time.Minute)
+	if len(periods) < 2 {
+		t.Fatalf("expected the cloudy hour to split the day, got %v", periods)
+	}
+	for _, p := range periods {
+		if p.Start.Before(cloudEnd) && p.End.After(cloudStart) {
+			t.Errorf("period %v overlaps the cloudy hour", p)
+		}
+	}
+
+	// without rescaling, a 15% low measurement fails the mean and max criteria
+	opts := DefaultClearSkyDetection()
+	opts.MaxIterations = 1
+	if mask, _ = st.DetectClearSky(opts); countTrue(mask) > countTrue(periodsMask(st, periods))/2 {
+		t.Error("expected far fewer clear minutes without rescaling")
+	}
+
+	clear, err := st.ClearSkyOnly(DefaultClearSkyDetection())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clear.Len() == 0 || clear.Len() >= st.Len() {
+		t.Errorf("ClearSkyOnly() kept %d of %d entries", clear.Len(), st.Len())
+	}
+}
+
+func countTrue(mask []bool) int {
+	n := 0
+	for _, m := range mask {
+		if m {
+			n++
+		}
+	}
+	return n
+}
+
+func periodsMask(st Station, periods []Period) []bool {
+	mask := make([]bool, st.Len())
+	for i, d := range st.Entries {
+		for _, p := range periods {
+			if !d.Timestamp.Before(p.Start) && !d.Timestamp.After(p.End) {
+				mask[i] = true
+			}
+		}
+	}
+	return mask
+}
+
+func TestDetectClearSkyGapsAndMissing(t *testing.T) {
+	st := syntheticDay(NewIneichen(3), 1, time.Time{}, time.Time{})
+
+	noon := 20 * 60
+	st.Entries[noon].DownwellingSolar = 0
+	st.Entries[noon].QC.DownwellingSolar = QCMissing
+	// drop five minutes to create a gap
+	st.Entries = append(st.Entries[:noon+30], st.Entries[noon+35:]...)
+
+	mask, err := st.DetectClearSky(DefaultClearSkyDetection())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mask[noon] {
+		t.Error("a missing value must never be clear")
+	}
+
+	periods := st.MaskPeriods(mask, time.Minute)
+	for _, p := range periods {
+		gapStart := st.Entries[noon+29].Timestamp
+		if !p.Start.After(gapStart) && p.End.After(gapStart) {
+			t.Errorf("period %v spans the gap", p)
+		}
+	}
+
+	if _, err = st.DetectClearSky(ClearSkyDetection{Window: 2 * time.Minute}); err != ErrWindowTooShort {
+		t.Errorf("expected ErrWindowTooShort, got %v", err)
+	}
+}
+
+func TestFilterAndMask(t *testing.T) {
+	st := Station{Entries: []Data{{TemperatureC: 1}, {TemperatureC: 2}, {TemperatureC: 3}}}
+
+	if got := st.Mask([]bool{true, false, true}); got.Len() != 2 || got.Entries[1].TemperatureC != 3 {
+		t.Errorf("Mask() == %+v", got.Entries)
+	}
+	if got := st.Filter(func(_ int, d Data) bool { return d.TemperatureC > 1 }); got.Len() != 2 {
+		t.Errorf("Filter() == %+v", got.Entries)
+	}
+	if st.Len() != 3 {
+		t.Error("Filter() must not modify the receiver")
+	}
+}
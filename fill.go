@@ -0,0 +1,283 @@
+package surfrad
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// FillMethod is how missing values of a variable are estimated from the valid values around them.
+type FillMethod int
+
+const (
+	FillNone          FillMethod = iota // leave missing
+	FillLinear                          // linear interpolation in time, along the shorter arc for wind direction
+	FillClearSkyIndex                   // linear interpolation of the clear-sky index, scaled by modeled clear-sky irradiance
+	FillNearest                         // the value of the nearest valid record, ties take the earlier one
+)
+
+func (m FillMethod) String() string {
+	switch m {
+	case FillNone:
+		return "none"
+	case FillLinear:
+		return "linear"
+	case FillClearSkyIndex:
+		return "clear-sky-index"
+	case FillNearest:
+		return "nearest"
+	default:
+		return fmt.Sprintf("FillMethod(%d)", int(m))
+	}
+}
+
+// ParseFillMethod parses the names returned by FillMethod.String.
+func ParseFillMethod(s string) (FillMethod, error) {
+	for _, m := range []FillMethod{FillNone, FillLinear, FillClearSkyIndex, FillNearest} {
+		if s == m.String() {
+			return m, nil
+		}
+	}
+	return FillNone, fmt.Errorf("unknown fill method %q", s)
+}
+
+// DefaultFillMethod returns the method used for a variable unless FillOptions overrides it: the clear-sky
+// index for solar irradiance, which follows the sun rather than a straight line, and linear interpolation
+// for everything else. The zenith angle is never filled, but is computed for inserted records.
+func DefaultFillMethod(name string) FillMethod {
+	switch name {
+	case "zen":
+		return FillNone
+	case "dw_solar", "uw_solar", "direct_n", "diffuse", "uvb", "par", "netsolar":
+		return FillClearSkyIndex
+	default:
+		return FillLinear
+	}
+}
+
+type FillOptions struct {
+	// Step is the sampling interval; zero uses Station.SamplingInterval.
+	Step time.Duration
+	// MaxGap is the longest run of missing values, or absent records, that is filled. Longer runs stay missing.
+	MaxGap time.Duration
+	// Model provides clear-sky irradiance for FillClearSkyIndex.
+	Model ClearSkyModel
+	// Methods overrides DefaultFillMethod by variable name.
+	Methods map[string]FillMethod
+}
+
+func DefaultFillOptions() FillOptions {
+	return FillOptions{
+		MaxGap: 15 * time.Minute,
+		Model:  NewIneichen(3),
+	}
+}
+
+func (o FillOptions) withDefaults() FillOptions {
+	def := DefaultFillOptions()
+	if o.MaxGap <= 0 {
+		o.MaxGap = def.MaxGap
+	}
+	if o.Model == nil {
+		o.Model = def.Model
+	}
+	return o
+}
+
+// methods returns the fill method of every entry of Variables.
+func (o FillOptions) methods() ([]FillMethod, error) {
+	for name := range o.Methods {
+		if _, ok := LookupVariable(name); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownVariable, name)
+		}
+	}
+	out := make([]FillMethod, len(Variables))
+	for i, v := range Variables {
+		m, ok := o.Methods[v.Name]
+		if !ok {
+			m = DefaultFillMethod(v.Name)
+		}
+		out[i] = m
+	}
+	return out, nil
+}
+
+// Provenance marks the values of a record that were filled rather than measured, one bit per entry of
+// Variables, and whether the whole record was inserted into a gap.
+type Provenance uint32
+
+// Inserted marks a record that was absent from the data. Its values not marked filled are missing.
+const Inserted Provenance = 1 << 31
+
+func variableBit(name string) Provenance {
+	if i := variableIndex(name); i >= 0 {
+		return 1 << i
+	}
+	return 0
+}
+
+// Filled reports whether the named variable was filled.
+func (p Provenance) Filled(name string) bool {
+	bit := variableBit(name)
+	return bit != 0 && p&bit != 0
+}
+
+// Measured reports whether the named variable comes from the data rather than filling or insertion.
+func (p Provenance) Measured(name string) bool {
+	return p&Inserted == 0 && !p.Filled(name)
+}
+
+// rawEntryTime returns the file's time columns for a timestamp, with the decimal hour rounded as in the files.
+func rawEntryTime(t time.Time) RawEntryTime {
+	t = t.UTC()
+	return RawEntryTime{
+		Year:    t.Year(),
+		Month:   int(t.Month()),
+		Day:     t.Day(),
+		JDay:    t.YearDay(),
+		Hour:    t.Hour(),
+		Minute:  t.Minute(),
+		Decimal: math.Round((float64(t.Hour())+float64(t.Minute())/60)*1000) / 1000,
+	}
+}
+
+// missingRecord returns a record at t with every measured value missing and the zenith angle computed.
+func missingRecord(t time.Time, loc Location) Data {
+	d := Data{RawTimestamp: rawEntryTime(t), Timestamp: t}
+	for _, v := range MeasuredVariables() {
+		v.Set(&d, 0, QCMissing)
+	}
+	d.SolarZenithAngle = loc.SunPosition(t.Add(-30 * time.Second)).ApparentZenith
+	return d
+}
+
+// clearSkyComponent picks the modeled irradiance that a variable is scaled by.
+func clearSkyComponent(name string, cs ClearSky) float64 {
+	switch name {
+	case "direct_n":
+		return cs.DNI
+	case "diffuse":
+		return cs.DHI
+	default:
+		return cs.GHI
+	}
+}
+
+func interpolate(method FillMethod, name string, a, b, f float64, csA, csB, cs float64) float64 {
+	switch method {
+	case FillNearest:
+		if f <= 0.5 {
+			return a
+		}
+		return b
+	case FillClearSkyIndex:
+		usable := func(v float64) bool { return v != MissingValue && v >= minClearSkyIrradiance }
+		if usable(csA) && usable(csB) && usable(cs) {
+			ka, kb := a/csA, b/csB
+			return (ka + f*(kb-ka)) * cs
+		}
+		// around sunrise and sunset the index is meaningless, so fall back to a straight line
+	}
+	if name == "winddir" {
+		diff := math.Mod(b-a+540, 360) - 180
+		return math.Mod(a+f*diff+360, 360)
+	}
+	return a + f*(b-a)
+}
+
+// Fill returns a copy of the station with short gaps filled, along with the provenance of every entry.
+// Absent records in gaps no longer than MaxGap are inserted, then each variable's runs of missing values
+// no longer than MaxGap and with valid values on both sides are filled by its FillMethod. A filled value
+// takes the QC flag of the nearest valid record. Entries are assumed to be sorted.
+func (s Station) Fill(opts FillOptions) (Station, []Provenance, error) {
+	opts = opts.withDefaults()
+	methods, err := opts.methods()
+	if err != nil {
+		return Station{}, nil, err
+	}
+
+	step := opts.Step
+	if step <= 0 {
+		step = s.SamplingInterval()
+	}
+	limit := int(opts.MaxGap / step)
+
+	out := s
+	out.Entries = make([]Data, 0, len(s.Entries))
+	var prov []Provenance
+	for i, d := range s.Entries {
+		if i > 0 {
+			prev := s.Entries[i-1].Timestamp
+			dt := d.Timestamp.Sub(prev)
+			if absent := int(dt/step) - 1; absent > 0 && absent <= limit && dt%step == 0 {
+				for t := prev.Add(step); t.Before(d.Timestamp); t = t.Add(step) {
+					out.Entries = append(out.Entries, missingRecord(t, s.LocatedAt))
+					prov = append(prov, Inserted|variableBit("zen"))
+				}
+			}
+		}
+		out.Entries = append(out.Entries, d)
+		prov = append(prov, 0)
+	}
+
+	var cs []ClearSky
+	for _, m := range methods {
+		if m == FillClearSkyIndex {
+			cs = out.ClearSky(opts.Model)
+			break
+		}
+	}
+
+	entries := out.Entries
+	for j, v := range Variables {
+		if methods[j] == FillNone {
+			continue
+		}
+		for i := 0; i < len(entries); {
+			if !v.Missing(&entries[i]) {
+				i++
+				continue
+			}
+			start := i
+			for i < len(entries) && v.Missing(&entries[i]) {
+				i++
+			}
+
+			before, after := start-1, i
+			if before < 0 || after == len(entries) || after-start > limit {
+				continue
+			}
+			t0, t1 := entries[before].Timestamp, entries[after].Timestamp
+			if t1.Sub(t0) != time.Duration(after-before)*step {
+				// a longer hole of absent records is inside the run
+				continue
+			}
+
+			a, b := v.Value(&entries[before]), v.Value(&entries[after])
+			for k := start; k < after; k++ {
+				f := float64(entries[k].Timestamp.Sub(t0)) / float64(t1.Sub(t0))
+
+				var csA, csB, csK float64
+				if cs != nil {
+					csA = clearSkyComponent(v.Name, cs[before])
+					csB = clearSkyComponent(v.Name, cs[after])
+					csK = clearSkyComponent(v.Name, cs[k])
+				}
+
+				qc := QCGood
+				if v.QC != nil {
+					nearest := after
+					if f <= 0.5 {
+						nearest = before
+					}
+					qc = v.QC(&entries[nearest])
+				}
+
+				v.Set(&entries[k], interpolate(methods[j], v.Name, a, b, f, csA, csB, csK), qc)
+				prov[k] |= 1 << j
+			}
+		}
+	}
+
+	return out, prov, nil
+}
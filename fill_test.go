@@ -0,0 +1,150 @@
+package surfrad
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseFillMethod(t *testing.T) {
+	for _, m := range []FillMethod{FillNone, FillLinear, FillClearSkyIndex, FillNearest} {
+		got, err := ParseFillMethod(m.String())
+		if err != nil || got != m {
+			t.Errorf("ParseFillMethod(%q) == %v, %v", m, got, err)
+		}
+	}
+	if _, err := ParseFillMethod("spline"); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	cases := []struct {
+		name       string
+		method     FillMethod
+		variable   string
+		a, b, f    float64
+		csA, csB   float64
+		cs, expect float64
+	}{
+		{"linear", FillLinear, "temp", 10, 20, 0.25, 0, 0, 0, 12.5},
+		{"wind direction wraps", FillLinear, "winddir", 350, 10, 0.5, 0, 0, 0, 0},
+		{"wind direction short way", FillLinear, "winddir", 10, 350, 0.25, 0, 0, 0, 5},
+		{"nearest before", FillNearest, "temp", 10, 20, 0.5, 0, 0, 0, 10},
+		{"nearest after", FillNearest, "temp", 10, 20, 0.6, 0, 0, 0, 20},
+		{"clear-sky index", FillClearSkyIndex, "dw_solar", 400, 900, 0.5, 500, 1000, 800, 680},
+		{"clear-sky index at night", FillClearSkyIndex, "dw_solar", 0, 4, 0.5, 0, 5, 2, 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := interpolate(tc.method, tc.variable, tc.a, tc.b, tc.f, tc.csA, tc.csB, tc.cs)
+			if math.Abs(got-tc.expect) > 1e-9 {
+				t.Errorf("got %v, expected %v", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestFill(t *testing.T) {
+	st := readTestStation(t)
+	orig := st.Entries
+
+	// a 10 minute hole around local noon, a 30 minute one that is too long, and 3 bad temperatures
+	st.Entries = append(append(append([]Data(nil), orig[:1140]...), orig[1150:1300]...), orig[1330:]...)
+	for i := 1200; i < 1203; i++ {
+		st.Entries[i].TemperatureC, st.Entries[i].QC.TemperatureC = 0, QCMissing
+	}
+	st.Entries[1199].QC.TemperatureC = QCQuestionable
+
+	opts := DefaultFillOptions()
+	filled, prov, err := st.Fill(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filled.Entries) != len(st.Entries)+10 || len(prov) != len(filled.Entries) {
+		t.Fatalf("expected 10 inserted records, got %d entries and %d provenance", len(filled.Entries), len(prov))
+	}
+	for i, d := range filled.Entries[:1300] {
+		if !d.Timestamp.Equal(orig[i].Timestamp) {
+			t.Fatalf("entry %d at %v, expected %v", i, d.Timestamp, orig[i].Timestamp)
+		}
+	}
+	if gaps := filled.Gaps(0); len(gaps) != 1 || gaps[0].Records != 30 {
+		t.Errorf("expected the long gap to remain, got %+v", gaps)
+	}
+
+	t.Run("inserted", func(t *testing.T) {
+		before, after := filled.Entries[1139], filled.Entries[1150]
+		csBefore := opts.Model.ClearSky(before.Timestamp.Add(-30*time.Second), st.LocatedAt).GHI
+		csAfter := opts.Model.ClearSky(after.Timestamp.Add(-30*time.Second), st.LocatedAt).GHI
+
+		for i := 1140; i < 1150; i++ {
+			d, p := filled.Entries[i], prov[i]
+			if p&Inserted == 0 || !p.Filled("zen") || !p.Filled("dw_solar") || !p.Filled("temp") || p.Measured("temp") {
+				t.Fatalf("unexpected provenance %b at %d", p, i)
+			}
+			if d.RawTimestamp != orig[i].RawTimestamp {
+				t.Errorf("raw time %+v, expected %+v", d.RawTimestamp, orig[i].RawTimestamp)
+			}
+			if math.Abs(d.SolarZenithAngle-orig[i].SolarZenithAngle) > 0.02 {
+				t.Errorf("zenith %v, expected %v", d.SolarZenithAngle, orig[i].SolarZenithAngle)
+			}
+
+			f := float64(i-1139) / 11
+			if want := before.TemperatureC + f*(after.TemperatureC-before.TemperatureC); math.Abs(d.TemperatureC-want) > 1e-9 {
+				t.Errorf("temperature %v, expected %v", d.TemperatureC, want)
+			}
+
+			cs := opts.Model.ClearSky(d.Timestamp.Add(-30*time.Second), st.LocatedAt).GHI
+			ka, kb := before.DownwellingSolar/csBefore, after.DownwellingSolar/csAfter
+			if want := (ka + f*(kb-ka)) * cs; math.Abs(d.DownwellingSolar-want) > 1e-9 {
+				t.Errorf("dw_solar %v, expected %v", d.DownwellingSolar, want)
+			}
+		}
+	})
+
+	t.Run("missing values", func(t *testing.T) {
+		for i, qc := range []int{QCQuestionable, QCQuestionable, QCGood} {
+			d, p := filled.Entries[1210+i], prov[1210+i]
+			if p&Inserted != 0 || !p.Filled("temp") || !p.Measured("rh") {
+				t.Errorf("unexpected provenance %b", p)
+			}
+			if d.QC.TemperatureC != qc || IsMissing(d.TemperatureC, d.QC.TemperatureC) {
+				t.Errorf("temperature %v flagged %d, expected flag %d", d.TemperatureC, d.QC.TemperatureC, qc)
+			}
+		}
+		if prov[1209] != 0 || prov[1213] != 0 {
+			t.Error("neighbours of the run should be measured")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		opts := DefaultFillOptions()
+		opts.Methods = map[string]FillMethod{"temp": FillNone}
+		filled, prov, err := st.Fill(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prov[1145].Filled("temp") || !IsMissing(filled.Entries[1145].TemperatureC, filled.Entries[1145].QC.TemperatureC) {
+			t.Error("temperature should stay missing")
+		}
+	})
+
+	t.Run("short limit", func(t *testing.T) {
+		filled, _, err := st.Fill(FillOptions{MaxGap: 5 * time.Minute})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(filled.Entries) != len(st.Entries) {
+			t.Error("no records should be inserted")
+		}
+		if d := filled.Entries[1201]; d.QC.TemperatureC != QCQuestionable || d.TemperatureC == 0 {
+			t.Errorf("the 3 minute run should be filled, got %+v", d.TemperatureC)
+		}
+	})
+
+	if _, _, err = st.Fill(FillOptions{Methods: map[string]FillMethod{"nope": FillLinear}}); !errors.Is(err, ErrUnknownVariable) {
+		t.Errorf("expected ErrUnknownVariable, got %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+package surfrad
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+	"time"
+)
+
+// GapOptions configures gap detection and completeness reporting.
+type GapOptions struct {
+	// Step is the sampling interval; zero uses Station.SamplingInterval.
+	Step time.Duration
+	// Variables to analyze by name; empty means every measured variable.
+	Variables []string
+	// LocalTime reports days and months in the station's local standard time instead of UTC.
+	LocalTime bool
+}
+
+// Gap is a run of missing records, or of missing values of one variable.
+type Gap struct {
+	Variable string        `json:"variable,omitempty"` // empty for missing records
+	Start    time.Time     `json:"start"`              // first missing timestamp
+	End      time.Time     `json:"end"`                // last missing timestamp
+	Records  int           `json:"records"`
+	Duration time.Duration `json:"duration"` // Records times the sampling interval
+}
+
+// SamplingInterval returns the most common interval between consecutive entries, or a minute if
+// there are fewer than two. Entries are assumed to be sorted.
+func (s Station) SamplingInterval() time.Duration {
+	counts := make(map[time.Duration]int)
+	var (
+		best  time.Duration = time.Minute
+		votes int
+	)
+	for i := 1; i < len(s.Entries); i++ {
+		dt := s.Entries[i].Timestamp.Sub(s.Entries[i-1].Timestamp)
+		if dt <= 0 {
+			continue
+		}
+		counts[dt]++
+		if counts[dt] > votes || (counts[dt] == votes && dt < best) {
+			best, votes = dt, counts[dt]
+		}
+	}
+	return best
+}
+
+// runs finds runs of absent records, and of present records for which missing returns true. An interval
+// that isn't a whole number of steps counts the records it most nearly holds, so jitter shorter than half
+// a step is no gap.
+func (s Station) runs(name string, step time.Duration, missing func(d *Data) bool) []Gap {
+	var (
+		out  []Gap
+		open bool
+	)
+
+	extend := func(start, end time.Time, records int) {
+		if !open {
+			out = append(out, Gap{Variable: name, Start: start})
+			open = true
+		}
+		out[len(out)-1].End = end
+		out[len(out)-1].Records += records
+	}
+
+	for i := range s.Entries {
+		d := &s.Entries[i]
+		if i > 0 {
+			prev := s.Entries[i-1].Timestamp
+			if absent := int(math.Round(float64(d.Timestamp.Sub(prev))/float64(step))) - 1; absent > 0 {
+				start := prev.Add(step)
+				extend(start, start.Add(time.Duration(absent-1)*step), absent)
+			}
+		}
+		if missing != nil && missing(d) {
+			extend(d.Timestamp, d.Timestamp, 1)
+			continue
+		}
+		open = false
+	}
+
+	for i := range out {
+		out[i].Duration = time.Duration(out[i].Records) * step
+	}
+	return out
+}
+
+// Gaps returns the runs of records absent from the entries, which are assumed to be sorted.
+// A zero step uses SamplingInterval.
+func (s Station) Gaps(step time.Duration) []Gap {
+	if step <= 0 {
+		step = s.SamplingInterval()
+	}
+	return s.runs("", step, nil)
+}
+
+// MissingRuns returns the runs where v is missing, counting absent records as missing.
+func (s Station) MissingRuns(v Variable, step time.Duration) []Gap {
+	if step <= 0 {
+		step = s.SamplingInterval()
+	}
+	return s.runs(v.Name, step, v.Missing)
+}
+
+// Availability is the share of a day or month's expected records that are present, overall and
+// per variable with a valid value.
+type Availability struct {
+	Start    time.Time          `json:"start"`
+	Expected int                `json:"expected"`
+	Records  int                `json:"records"`
+	Percent  map[string]float64 `json:"percent"`
+}
+
+// Completeness summarizes where a station's data is missing.
+type Completeness struct {
+	Station   StationName   `json:"station"`
+	Start     time.Time     `json:"start"`
+	End       time.Time     `json:"end"`
+	Step      time.Duration `json:"step"`
+	Expected  int           `json:"expected"` // records from Start through End
+	Records   int           `json:"records"`
+	Variables []string      `json:"variables"`
+
+	Gaps    []Gap          `json:"gaps"`    // absent records
+	Missing []Gap          `json:"missing"` // missing values per variable, including absent records
+	Daily   []Availability `json:"daily"`
+	Monthly []Availability `json:"monthly"`
+}
+
+func (o GapOptions) variables() ([]Variable, error) {
+	if len(o.Variables) == 0 {
+		return MeasuredVariables(), nil
+	}
+	return SelectVariables(o.Variables)
+}
+
+// availability buckets the entries into consecutive periods starting at periodStart and ending at next.
+func (s Station) availability(vars []Variable, step time.Duration, periodStart func(time.Time) time.Time,
+	next func(time.Time) time.Time) []Availability {
+
+	if len(s.Entries) == 0 {
+		return nil
+	}
+
+	var out []Availability
+	first := periodStart(s.Entries[0].Timestamp)
+	last := s.Entries[len(s.Entries)-1].Timestamp
+	for start := first; !start.After(last); start = next(start) {
+		out = append(out, Availability{
+			Start:    start,
+			Expected: max(1, int(next(start).Sub(start)/step)), // a step longer than the period still expects one
+			Percent:  make(map[string]float64, len(vars)),
+		})
+	}
+
+	valid := make([][]int, len(out))
+	for i := range valid {
+		valid[i] = make([]int, len(vars))
+	}
+
+	period := 0
+	for i := range s.Entries {
+		d := &s.Entries[i]
+		for period < len(out)-1 && !d.Timestamp.Before(out[period+1].Start) {
+			period++
+		}
+		out[period].Records++
+		for j, v := range vars {
+			if !v.Missing(d) {
+				valid[period][j]++
+			}
+		}
+	}
+
+	for i := range out {
+		for j, v := range vars {
+			out[i].Percent[v.Name] = 100 * float64(valid[i][j]) / float64(out[i].Expected)
+		}
+	}
+	return out
+}
+
+// Completeness analyzes gaps and availability. Entries are assumed to be sorted, as after Merge.
+func (s Station) Completeness(opts GapOptions) (Completeness, error) {
+	vars, err := opts.variables()
+	if err != nil {
+		return Completeness{}, err
+	}
+
+	step := opts.Step
+	if step <= 0 {
+		step = s.SamplingInterval()
+	}
+	tz := time.UTC
+	if opts.LocalTime {
+		tz = s.TimeZone()
+	}
+
+	c := Completeness{Station: s.StationName, Step: step, Records: len(s.Entries), Gaps: s.Gaps(step)}
+	for _, v := range vars {
+		c.Variables = append(c.Variables, v.Name)
+		c.Missing = append(c.Missing, s.MissingRuns(v, step)...)
+	}
+	for _, gaps := range [][]Gap{c.Gaps, c.Missing} {
+		for i := range gaps {
+			gaps[i].Start, gaps[i].End = gaps[i].Start.In(tz), gaps[i].End.In(tz)
+		}
+	}
+	if len(s.Entries) == 0 {
+		return c, nil
+	}
+
+	c.Start, c.End = s.Entries[0].Timestamp.In(tz), s.Entries[len(s.Entries)-1].Timestamp.In(tz)
+	c.Expected = int(c.End.Sub(c.Start)/step) + 1
+
+	c.Daily = s.availability(vars, step,
+		func(t time.Time) time.Time { return LocalDay(t, tz) },
+		func(t time.Time) time.Time { return t.AddDate(0, 0, 1) })
+	c.Monthly = s.availability(vars, step,
+		func(t time.Time) time.Time {
+			t = t.In(tz)
+			return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, tz)
+		},
+		func(t time.Time) time.Time { return t.AddDate(0, 1, 0) })
+
+	return c, nil
+}
+
+// WriteText writes a compact plain text version of the report.
+func (c Completeness) WriteText(w io.Writer) error {
+	pct := 0.0
+	if c.Expected > 0 {
+		pct = 100 * float64(c.Records) / float64(c.Expected)
+	}
+	_, _ = fmt.Fprintf(w, "station: %s\n", c.Station)
+	_, _ = fmt.Fprintf(w, "span: %s to %s, %s step\n", c.Start.Format(time.RFC3339), c.End.Format(time.RFC3339), c.Step)
+	_, _ = fmt.Fprintf(w, "records: %d of %d expected (%.1f%%)\n", c.Records, c.Expected, pct)
+
+	_, _ = fmt.Fprintf(w, "gaps: %d\n", len(c.Gaps))
+	for _, g := range c.Gaps {
+		_, _ = fmt.Fprintf(w, "  %s to %s (%d records, %s)\n", g.Start.Format(time.RFC3339), g.End.Format(time.RFC3339), g.Records, g.Duration)
+	}
+
+	runs := make(map[string]int)
+	longest := make(map[string]time.Duration)
+	for _, g := range c.Missing {
+		runs[g.Variable]++
+		if g.Duration > longest[g.Variable] {
+			longest[g.Variable] = g.Duration
+		}
+	}
+
+	_, _ = fmt.Fprintln(w, "\nmissing runs:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  variable\truns\tlongest")
+	for _, v := range c.Variables {
+		_, _ = fmt.Fprintf(tw, "  %s\t%d\t%s\n", v, runs[v], longest[v])
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	writeTable := func(title, layout string, periods []Availability) error {
+		_, _ = fmt.Fprintf(w, "\n%s availability (%%):\n", title)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprint(tw, "  \trecords")
+		for _, v := range c.Variables {
+			_, _ = fmt.Fprintf(tw, "\t%s", v)
+		}
+		_, _ = fmt.Fprintln(tw)
+		for _, a := range periods {
+			_, _ = fmt.Fprintf(tw, "  %s\t%.1f", a.Start.Format(layout), 100*float64(a.Records)/float64(a.Expected))
+			for _, v := range c.Variables {
+				_, _ = fmt.Fprintf(tw, "\t%.1f", a.Percent[v])
+			}
+			_, _ = fmt.Fprintln(tw)
+		}
+		return tw.Flush()
+	}
+
+	if err := writeTable("monthly", "2006-01", c.Monthly); err != nil {
+		return err
+	}
+	return writeTable("daily", time.DateOnly, c.Daily)
+}
@@ -0,0 +1,136 @@
+package surfrad
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gappyStation(t *testing.T) Station {
+	t.Helper()
+	st := readTestStation(t)
+	// drop 30 records after 01:40 and knock out 5 temperatures
+	st.Entries = append(append([]Data(nil), st.Entries[:100]...), st.Entries[130:]...)
+	for i := 500; i < 505; i++ {
+		st.Entries[i].TemperatureC, st.Entries[i].QC.TemperatureC = 0, QCMissing
+	}
+	return st
+}
+
+func TestGaps(t *testing.T) {
+	st := gappyStation(t)
+
+	if step := st.SamplingInterval(); step != time.Minute {
+		t.Fatalf("SamplingInterval() == %v", step)
+	}
+
+	gaps := st.Gaps(0)
+	if len(gaps) != 1 {
+		t.Fatalf("expected one gap, got %+v", gaps)
+	}
+	g := gaps[0]
+	if !g.Start.Equal(st.Entries[99].Timestamp.Add(time.Minute)) || !g.End.Equal(st.Entries[100].Timestamp.Add(-time.Minute)) ||
+		g.Records != 30 || g.Duration != 30*time.Minute {
+		t.Errorf("unexpected gap %+v", g)
+	}
+
+	temp, _ := LookupVariable("temp")
+	runs := st.MissingRuns(temp, time.Minute)
+	if len(runs) != 2 || runs[0].Records != 30 || runs[1].Records != 5 || runs[1].Variable != "temp" ||
+		!runs[1].Start.Equal(st.Entries[500].Timestamp) {
+		t.Errorf("unexpected temperature runs %+v", runs)
+	}
+
+	// a missing value next to a gap joins it
+	st.Entries[100].TemperatureC, st.Entries[100].QC.TemperatureC = 0, QCMissing
+	if runs = st.MissingRuns(temp, time.Minute); runs[0].Records != 31 {
+		t.Errorf("expected the missing value to extend the gap: %+v", runs[0])
+	}
+}
+
+func TestGapsIrregular(t *testing.T) {
+	base := time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC)
+	var st Station
+	for _, offset := range []time.Duration{0, 60, 130, 220, 370, 430} {
+		st.Entries = append(st.Entries, Data{Timestamp: base.Add(offset * time.Second)})
+	}
+
+	// 70s is jitter, 90s holds one record and 150s two
+	gaps := st.Gaps(time.Minute)
+	if len(gaps) != 2 || gaps[0].Records != 1 || gaps[1].Records != 2 {
+		t.Fatalf("unexpected gaps %+v", gaps)
+	}
+	for _, g := range gaps {
+		if g.End.Before(g.Start) || g.Duration != time.Duration(g.Records)*time.Minute {
+			t.Errorf("inconsistent gap %+v", g)
+		}
+	}
+}
+
+func TestCompleteness(t *testing.T) {
+	st := gappyStation(t)
+
+	c, err := st.Completeness(GapOptions{Variables: []string{"temp", "rh"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Expected != 1440 || c.Records != 1410 || len(c.Gaps) != 1 || len(c.Missing) != 3 {
+		t.Errorf("unexpected summary: expected %d records %d gaps %d missing %d", c.Expected, c.Records, len(c.Gaps), len(c.Missing))
+	}
+	if len(c.Daily) != 1 || len(c.Monthly) != 1 {
+		t.Fatalf("expected one day and one month, got %d and %d", len(c.Daily), len(c.Monthly))
+	}
+	day := c.Daily[0]
+	if day.Expected != 1440 || day.Records != 1410 {
+		t.Errorf("unexpected day %+v", day)
+	}
+	if want := 100 * 1405.0 / 1440; day.Percent["temp"] != want {
+		t.Errorf("temp availability %v, expected %v", day.Percent["temp"], want)
+	}
+	if month := c.Monthly[0]; month.Expected != 29*1440 || !month.Start.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected month %+v", month)
+	}
+
+	local, err := st.Completeness(GapOptions{LocalTime: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(local.Daily) != 2 || local.Daily[0].Start.Location() != st.TimeZone() || len(local.Variables) != 20 {
+		t.Errorf("expected two local days for every measured variable, got %d days, %v", len(local.Daily), local.Variables)
+	}
+
+	var buf bytes.Buffer
+	if err = c.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"records: 1410 of 1440 expected (97.9%)", "gaps: 1", "(30 records, 30m0s)", "2024-02-17", "97.6"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("text report missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	if _, err = json.Marshal(c); err != nil {
+		t.Error(err)
+	}
+
+	// a step longer than a day still expects a record a day
+	coarse, err := st.Completeness(GapOptions{Step: 48 * time.Hour, Variables: []string{"temp"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range append(coarse.Daily, coarse.Monthly...) {
+		if a.Expected < 1 || math.IsInf(a.Percent["temp"], 0) || math.IsNaN(a.Percent["temp"]) {
+			t.Errorf("unexpected coarse availability %+v", a)
+		}
+	}
+	if _, err = json.Marshal(coarse); err != nil {
+		t.Error(err)
+	}
+
+	if _, err = st.Completeness(GapOptions{Variables: []string{"nope"}}); err == nil {
+		t.Error("expected an error for an unknown variable")
+	}
+}
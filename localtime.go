@@ -0,0 +1,51 @@
+package surfrad
+
+import (
+	"math"
+	"time"
+)
+
+// MeanSolarTime returns t as local mean time at longitude (decimal degrees, east positive), where
+// the mean sun crosses the meridian at 12:00. The result is in a fixed zone named "LMT".
+func MeanSolarTime(t time.Time, longitude float64) time.Time {
+	offset := int(math.Round(4 * longitude * 60))
+	return t.In(time.FixedZone("LMT", offset))
+}
+
+// TrueSolarTime returns t as apparent solar time at longitude, where the true sun crosses the meridian
+// at 12:00. It differs from mean solar time by the equation of time, so the zone offset, named "LAT",
+// varies through the year.
+func TrueSolarTime(t time.Time, longitude float64) time.Time {
+	offset := int(math.Round((4*longitude + EquationOfTime(t)) * 60))
+	return t.In(time.FixedZone("LAT", offset))
+}
+
+// LocalStandardTime returns the record's timestamp in the given time zone, normally Station.TimeZone.
+func (d Data) LocalStandardTime(tz *time.Location) time.Time {
+	return d.Timestamp.In(tz)
+}
+
+// MeanSolarTime returns the record's timestamp as local mean time at loc.
+func (d Data) MeanSolarTime(loc Location) time.Time {
+	return MeanSolarTime(d.Timestamp, loc.Longitude)
+}
+
+// TrueSolarTime returns the record's timestamp as apparent solar time at loc.
+func (d Data) TrueSolarTime(loc Location) time.Time {
+	return TrueSolarTime(d.Timestamp, loc.Longitude)
+}
+
+// TruncateIn rounds t down to a multiple of width since midnight in tz, so daily bins start at local
+// midnight rather than UTC midnight. The result is in tz.
+func TruncateIn(t time.Time, width time.Duration, tz *time.Location) time.Time {
+	t = t.In(tz)
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(width).Add(-shift).In(tz)
+}
+
+// LocalDay returns local midnight in tz of the day containing t.
+func LocalDay(t time.Time, tz *time.Location) time.Time {
+	t = t.In(tz)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, tz)
+}
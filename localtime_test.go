@@ -0,0 +1,81 @@
+package surfrad
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSolarTime(t *testing.T) {
+	ts := time.Date(2024, 2, 17, 20, 0, 0, 0, time.UTC)
+
+	if got := MeanSolarTime(ts, -120); got.Hour() != 12 || got.Minute() != 0 {
+		t.Errorf("MeanSolarTime() == %v, expected 12:00", got)
+	}
+
+	// mid February the sun runs about 14 minutes behind the mean sun
+	got := TrueSolarTime(ts, -120)
+	if got.Hour() != 11 || got.Minute() < 45 || got.Minute() > 47 {
+		t.Errorf("TrueSolarTime() == %v, expected about 11:46", got)
+	}
+	if !got.Equal(ts) {
+		t.Error("conversions must not change the instant")
+	}
+
+	// at solar noon true solar time reads 12:00
+	noon := desertRock.SunEvents(ts, time.UTC).SolarNoon
+	if lat := (Data{Timestamp: noon}).TrueSolarTime(desertRock); lat.Hour() != 12 || lat.Minute() != 0 {
+		t.Errorf("true solar time at solar noon == %v", lat)
+	}
+
+	pst := StationIDToTimeZone[StationIDDesertRock]
+	if local := (Data{Timestamp: ts}).LocalStandardTime(pst); local.Hour() != 12 || local.Location() != pst {
+		t.Errorf("LocalStandardTime() == %v", local)
+	}
+}
+
+func TestTruncateIn(t *testing.T) {
+	pst := StationIDToTimeZone[StationIDDesertRock]
+	ts := time.Date(2024, 2, 17, 5, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		width    time.Duration
+		tz       *time.Location
+		expected time.Time
+	}{
+		{"utc day", 24 * time.Hour, time.UTC, time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC)},
+		{"local day", 24 * time.Hour, pst, time.Date(2024, 2, 16, 0, 0, 0, 0, pst)},
+		{"local hour", time.Hour, pst, time.Date(2024, 2, 16, 21, 0, 0, 0, pst)},
+		{"half hour offset", 2 * time.Hour, time.FixedZone("ACST", 9*3600+1800), time.Date(2024, 2, 17, 14, 0, 0, 0, time.FixedZone("ACST", 9*3600+1800))},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := TruncateIn(ts, tc.width, tc.tz); !got.Equal(tc.expected) {
+				t.Errorf("TruncateIn() == %v, expected %v", got, tc.expected)
+			}
+		})
+	}
+
+	if day := LocalDay(ts, pst); !day.Equal(time.Date(2024, 2, 16, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("LocalDay() == %v", day)
+	}
+}
+
+func TestLocalDayAggregation(t *testing.T) {
+	station := readTestStation(t)
+
+	opts := DefaultClearnessOptions()
+	if n := len(station.BinnedClearness(24*time.Hour, opts)); n != 1 {
+		t.Errorf("expected 1 UTC day, got %d", n)
+	}
+	opts.LocalTime = true
+	days := station.BinnedClearness(24*time.Hour, opts)
+	if len(days) != 2 || days[1].Timestamp.Hour() != 0 || days[1].Timestamp.Location() != station.TimeZone() {
+		t.Errorf("expected 2 local days starting at local midnight, got %+v", days)
+	}
+
+	health := station.PyrgeometerHealth(PyrgeometerOptions{LocalTime: true})
+	if len(health) != 2 || health[0].Downwelling.Records+health[1].Downwelling.Records != station.Len() {
+		t.Errorf("expected 2 local days covering every record, got %+v", health)
+	}
+}
@@ -0,0 +1,193 @@
+package surfrad
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// DuplicatePolicy decides which record to keep when several share a timestamp.
+type DuplicatePolicy int
+
+const (
+	KeepFirst        DuplicatePolicy = iota // the record from the earliest input
+	KeepLast                                // the record from the latest input
+	RejectDuplicates                        // fail the merge
+	PreferGoodQC                            // the record with the most values flagged good, ties keep the first
+)
+
+func (p DuplicatePolicy) String() string {
+	switch p {
+	case KeepFirst:
+		return "first"
+	case KeepLast:
+		return "last"
+	case RejectDuplicates:
+		return "error"
+	case PreferGoodQC:
+		return "prefer-good-qc"
+	default:
+		return fmt.Sprintf("DuplicatePolicy(%d)", int(p))
+	}
+}
+
+// ParseDuplicatePolicy parses the names returned by DuplicatePolicy.String.
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	for _, p := range []DuplicatePolicy{KeepFirst, KeepLast, RejectDuplicates, PreferGoodQC} {
+		if s == p.String() {
+			return p, nil
+		}
+	}
+	return KeepFirst, fmt.Errorf("unknown duplicate policy %q", s)
+}
+
+var (
+	ErrStationMismatch = errors.New("stations differ")
+	ErrDuplicate       = errors.New("duplicate timestamp")
+	ErrHeaderDrift     = errors.New("header differs between inputs")
+)
+
+type MergeOptions struct {
+	Duplicates DuplicatePolicy
+	// StrictHeaders fails the merge on any header drift instead of only reporting it.
+	StrictHeaders bool
+}
+
+// HeaderDrift is a header field that changed from one input to the next, in time order.
+type HeaderDrift struct {
+	Input int    `json:"input"` // index into the inputs passed to Merge
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+func (h HeaderDrift) String() string {
+	return fmt.Sprintf("input %d: %s changed from %s to %s", h.Input, h.Field, h.From, h.To)
+}
+
+type MergeReport struct {
+	Inputs     int           `json:"inputs"`
+	Records    int           `json:"records"`    // records in the merged station
+	Duplicates int           `json:"duplicates"` // records dropped as duplicates
+	Drift      []HeaderDrift `json:"drift,omitempty"`
+}
+
+// Good returns how many of the flags are QCGood.
+func (q QCFlags) Good() int {
+	var n int
+	v := reflect.ValueOf(q)
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).Int() == QCGood {
+			n++
+		}
+	}
+	return n
+}
+
+func headerDrift(input int, prev, cur Station) []HeaderDrift {
+	var drift []HeaderDrift
+	check := func(field string, from, to any) {
+		if from != to {
+			drift = append(drift, HeaderDrift{Input: input, Field: field, From: fmt.Sprint(from), To: fmt.Sprint(to)})
+		}
+	}
+	check("latitude", prev.LocatedAt.Latitude, cur.LocatedAt.Latitude)
+	check("longitude", prev.LocatedAt.Longitude, cur.LocatedAt.Longitude)
+	check("elevation", prev.LocatedAt.Elevation, cur.LocatedAt.Elevation)
+	check("version", prev.Version, cur.Version)
+	return drift
+}
+
+func firstTimestamp(s Station) time.Time {
+	if len(s.Entries) == 0 {
+		return time.Time{}
+	}
+	first := s.Entries[0].Timestamp
+	for _, d := range s.Entries[1:] {
+		if d.Timestamp.Before(first) {
+			first = d.Timestamp
+		}
+	}
+	return first
+}
+
+// Merge combines stations, typically parsed from consecutive daily files, into one with its entries in
+// time order. All inputs must be the same station. Header fields that change between inputs are reported
+// as drift; the merged station takes its header from the chronologically first input.
+func Merge(opts MergeOptions, stations ...Station) (Station, MergeReport, error) {
+	report := MergeReport{Inputs: len(stations)}
+	if len(stations) == 0 {
+		return Station{}, report, nil
+	}
+
+	// order the inputs by their first record, so drift and KeepFirst/KeepLast follow time. Empty inputs
+	// have no time and are left out, unless every input is empty.
+	order := make([]int, 0, len(stations))
+	for i, st := range stations {
+		if len(st.Entries) > 0 {
+			order = append(order, i)
+		}
+	}
+	if len(order) == 0 {
+		order = append(order, 0)
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return firstTimestamp(stations[order[a]]).Before(firstTimestamp(stations[order[b]]))
+	})
+
+	first := stations[order[0]]
+	// empty inputs are checked too, though they take no part in the ordering
+	for i, st := range stations {
+		if st.StationName != first.StationName {
+			return Station{}, report, fmt.Errorf("%w: input %d is %q, expected %q", ErrStationMismatch, i, st.StationName, first.StationName)
+		}
+	}
+	var total int
+	for n, i := range order {
+		st := stations[i]
+		if n > 0 {
+			report.Drift = append(report.Drift, headerDrift(i, stations[order[n-1]], st)...)
+		}
+		total += len(st.Entries)
+	}
+	if opts.StrictHeaders && len(report.Drift) > 0 {
+		return Station{}, report, fmt.Errorf("%w: %s", ErrHeaderDrift, report.Drift[0])
+	}
+
+	// a stable sort keeps records sharing a timestamp in input order
+	records := make([]Data, 0, total)
+	for _, i := range order {
+		records = append(records, stations[i].Entries...)
+	}
+	sort.SliceStable(records, func(a, b int) bool {
+		return records[a].Timestamp.Before(records[b].Timestamp)
+	})
+
+	merged := first
+	merged.Entries = make([]Data, 0, len(records))
+
+	for i, d := range records {
+		n := len(merged.Entries)
+		if i == 0 || !d.Timestamp.Equal(merged.Entries[n-1].Timestamp) {
+			merged.Entries = append(merged.Entries, d)
+			continue
+		}
+
+		report.Duplicates++
+		switch opts.Duplicates {
+		case RejectDuplicates:
+			return Station{}, report, fmt.Errorf("%w: %s", ErrDuplicate, d.Timestamp.Format(time.RFC3339))
+		case KeepLast:
+			merged.Entries[n-1] = d
+		case PreferGoodQC:
+			if d.QC.Good() > merged.Entries[n-1].QC.Good() {
+				merged.Entries[n-1] = d
+			}
+		}
+	}
+
+	report.Records = len(merged.Entries)
+	return merged, report, nil
+}
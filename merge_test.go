@@ -0,0 +1,114 @@
+package surfrad
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	station := readTestStation(t)
+
+	// two overlapping halves, passed out of order
+	early, late := station, station
+	early.Entries = station.Entries[:800]
+	late.Entries = append([]Data(nil), station.Entries[700:]...)
+	for i := range late.Entries[:100] {
+		late.Entries[i].TemperatureC = 99
+	}
+	// the later file has one bad value the earlier one doesn't
+	late.Entries[0].QC.RelativeHumidity = QCBad
+
+	cases := []struct {
+		policy     DuplicatePolicy
+		expected   float64 // temperature at the first overlapping record
+		overlapEnd float64 // temperature at the last overlapping record
+	}{
+		{KeepFirst, station.Entries[700].TemperatureC, station.Entries[799].TemperatureC},
+		{KeepLast, 99, 99},
+		{PreferGoodQC, station.Entries[700].TemperatureC, station.Entries[799].TemperatureC},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			merged, report, err := Merge(MergeOptions{Duplicates: tc.policy}, late, early)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if merged.Len() != station.Len() || report.Records != station.Len() || report.Duplicates != 100 {
+				t.Fatalf("merged %d records with %d duplicates: %+v", merged.Len(), report.Duplicates, report)
+			}
+			for i := 1; i < merged.Len(); i++ {
+				if !merged.Entries[i].Timestamp.After(merged.Entries[i-1].Timestamp) {
+					t.Fatalf("entries out of order at %d", i)
+				}
+			}
+			if merged.Entries[700].TemperatureC != tc.expected || merged.Entries[799].TemperatureC != tc.overlapEnd {
+				t.Errorf("unexpected resolution: %v, %v", merged.Entries[700].TemperatureC, merged.Entries[799].TemperatureC)
+			}
+			if len(report.Drift) != 0 {
+				t.Errorf("unexpected drift: %v", report.Drift)
+			}
+		})
+	}
+
+	if _, _, err := Merge(MergeOptions{Duplicates: RejectDuplicates}, early, late); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("expected ErrDuplicate, got %v", err)
+	}
+}
+
+func TestMergeHeaders(t *testing.T) {
+	station := readTestStation(t)
+
+	first, second := station, station
+	first.Entries = station.Entries[:720]
+	second.Entries = station.Entries[720:]
+	second.Version = 2
+	second.LocatedAt.Elevation = 1010
+
+	merged, report, err := Merge(MergeOptions{}, first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Drift) != 2 || report.Drift[0].Input != 1 || report.Drift[0].Field != "elevation" || report.Drift[1].To != "2" {
+		t.Errorf("unexpected drift: %+v", report.Drift)
+	}
+	if merged.Version != station.Version || merged.Len() != station.Len() {
+		t.Errorf("merged header should come from the first input: %+v", merged.LocatedAt)
+	}
+
+	if _, _, err = Merge(MergeOptions{StrictHeaders: true}, first, second); !errors.Is(err, ErrHeaderDrift) {
+		t.Errorf("expected ErrHeaderDrift, got %v", err)
+	}
+
+	// an empty input, such as a header-only file, neither sets the header nor counts as drift
+	empty := Station{StationName: station.StationName}
+	merged, report, err = Merge(MergeOptions{}, second, empty, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.StationName != station.StationName || merged.Version != station.Version || merged.Len() != station.Len() ||
+		len(report.Drift) != 2 {
+		t.Errorf("empty input changed the merge: %+v, drift %+v", merged.LocatedAt, report.Drift)
+	}
+
+	other := second
+	other.StationName = StationBondville
+	if _, _, err = Merge(MergeOptions{}, first, other); !errors.Is(err, ErrStationMismatch) {
+		t.Errorf("expected ErrStationMismatch, got %v", err)
+	}
+	other.Entries = nil
+	if _, _, err = Merge(MergeOptions{}, first, other); !errors.Is(err, ErrStationMismatch) {
+		t.Errorf("expected ErrStationMismatch for an empty input of another station, got %v", err)
+	}
+}
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	for _, p := range []DuplicatePolicy{KeepFirst, KeepLast, RejectDuplicates, PreferGoodQC} {
+		if got, err := ParseDuplicatePolicy(p.String()); err != nil || got != p {
+			t.Errorf("ParseDuplicatePolicy(%q) == %v, %v", p, got, err)
+		}
+	}
+	if _, err := ParseDuplicatePolicy("newest"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
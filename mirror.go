@@ -0,0 +1,376 @@
+package surfrad
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const ManifestName = "manifest.json"
+
+var ErrCorrupt = errors.New("corrupt file")
+
+// ManifestEntry describes one mirrored daily file.
+type ManifestEntry struct {
+	Size         int64     `json:"size"`
+	SHA256       string    `json:"sha256"`
+	Records      int       `json:"records"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+}
+
+// Manifest records every mirrored file keyed by its slash separated path relative to the mirror root.
+type Manifest struct {
+	Files map[string]ManifestEntry `json:"files"`
+}
+
+// Mirror keeps a local directory tree in sync with the archive layout: <Root>/<station dir>/<year>/<file>.
+type Mirror struct {
+	Client *Client
+	Root   string
+
+	mu       sync.Mutex
+	manifest *Manifest
+}
+
+func NewMirror(root string, client *Client) *Mirror {
+	if client == nil {
+		client = NewClient()
+	}
+	return &Mirror{Client: client, Root: root}
+}
+
+// SyncResult lists the relative paths touched by a Sync.
+type SyncResult struct {
+	Downloaded []string `json:"downloaded"`
+	Unchanged  []string `json:"unchanged"`
+	Repaired   []string `json:"repaired"` // local copy was corrupt and has been replaced
+	Missing    []string `json:"missing"`  // not present in the archive
+}
+
+func (m *Mirror) relPath(sid StationID, day time.Time) (string, error) {
+	dir, ok := GetStationDirectory(sid)
+	if !ok {
+		return "", fmt.Errorf("invalid or unknown station id: %s", sid)
+	}
+	return dir + "/" + strconv.Itoa(day.UTC().Year()) + "/" + FileName(sid, day), nil
+}
+
+// LoadManifest reads the manifest from the mirror root, returning an empty one if none exists yet.
+func (m *Mirror) LoadManifest() (*Manifest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.loadManifest()
+}
+
+func (m *Mirror) loadManifest() (*Manifest, error) {
+	if m.manifest != nil {
+		return m.manifest, nil
+	}
+
+	manifest := &Manifest{Files: make(map[string]ManifestEntry)}
+
+	data, err := os.ReadFile(filepath.Join(m.Root, ManifestName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err = json.Unmarshal(data, manifest); err != nil {
+			return nil, fmt.Errorf("error parsing manifest: %w", err)
+		}
+		if manifest.Files == nil {
+			manifest.Files = make(map[string]ManifestEntry)
+		}
+	}
+
+	m.manifest = manifest
+	return manifest, nil
+}
+
+func (m *Mirror) saveManifest() error {
+	m.mu.Lock()
+	data, err := json.MarshalIndent(m.manifest, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(m.Root, ManifestName), data)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkContents re-parses a daily file. A file is considered corrupt if it cannot be parsed at all,
+// or if it parses to a different number of records than when it was downloaded.
+func checkContents(data []byte, expectedRecords int) (int, error) {
+	station, err := ReadData(bytes.NewReader(data))
+	switch {
+	case !station.StationName.Valid():
+		return 0, fmt.Errorf("%w: invalid station name %q", ErrCorrupt, station.StationName)
+	case station.Len() == 0:
+		return 0, fmt.Errorf("%w: no records: %v", ErrCorrupt, err)
+	case expectedRecords > 0 && station.Len() != expectedRecords:
+		return station.Len(), fmt.Errorf("%w: %d records, expected %d", ErrCorrupt, station.Len(), expectedRecords)
+	}
+	return station.Len(), nil
+}
+
+// verifyLocal checks a local file against its manifest entry.
+func (m *Mirror) verifyLocal(rel string, entry ManifestEntry) error {
+	data, err := os.ReadFile(filepath.Join(m.Root, filepath.FromSlash(rel)))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) != entry.Size || checksum(data) != entry.SHA256 {
+		return fmt.Errorf("%w: checksum mismatch", ErrCorrupt)
+	}
+	_, err = checkContents(data, entry.Records)
+	return err
+}
+
+// quarantine moves a local file that failed verification aside, to the same name with .corrupt
+// appended, and drops it from the manifest.
+func (m *Mirror) quarantine(rel string) error {
+	path := filepath.Join(m.Root, filepath.FromSlash(rel))
+	if err := os.Rename(path, path+".corrupt"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	m.mu.Lock()
+	delete(m.manifest.Files, rel)
+	m.mu.Unlock()
+	return nil
+}
+
+type syncOutcome int
+
+const (
+	outcomeDownloaded syncOutcome = iota
+	outcomeUnchanged
+	outcomeRepaired
+	outcomeMissing
+)
+
+func (m *Mirror) syncDay(ctx context.Context, sid StationID, day time.Time) (string, syncOutcome, error) {
+	rel, err := m.relPath(sid, day)
+	if err != nil {
+		return "", 0, err
+	}
+	url, err := m.Client.URL(sid, day)
+	if err != nil {
+		return rel, 0, err
+	}
+
+	m.mu.Lock()
+	entry, known := m.manifest.Files[rel]
+	m.mu.Unlock()
+
+	localErr := errors.New("not mirrored")
+	if known {
+		localErr = m.verifyLocal(rel, entry)
+	}
+
+	if localErr == nil {
+		resp, _, err := m.Client.request(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return rel, outcomeUnchanged, nil // removed upstream, keep our copy
+			}
+			return rel, 0, err
+		}
+		if !remoteChanged(resp, entry) {
+			return rel, outcomeUnchanged, nil
+		}
+	}
+
+	resp, body, err := m.Client.request(ctx, http.MethodGet, url, nil)
+	if errors.Is(err, ErrNotFound) {
+		if localErr == nil {
+			return rel, outcomeUnchanged, nil // removed upstream since the HEAD, keep our copy
+		}
+		if known {
+			// the local copy failed verification and can't be replaced, so stop trusting it
+			if err = m.quarantine(rel); err != nil {
+				return rel, 0, err
+			}
+		}
+		return rel, outcomeMissing, nil
+	}
+	if err != nil {
+		return rel, 0, err
+	}
+
+	records, err := checkContents(body, 0)
+	if err != nil {
+		return rel, 0, fmt.Errorf("downloaded %s: %w", url, err)
+	}
+
+	if err = writeFileAtomic(filepath.Join(m.Root, filepath.FromSlash(rel)), body); err != nil {
+		return rel, 0, err
+	}
+
+	entry = ManifestEntry{
+		Size:    int64(len(body)),
+		SHA256:  checksum(body),
+		Records: records,
+		Fetched: time.Now().UTC(),
+	}
+	if lm, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		entry.LastModified = lm.UTC()
+	}
+
+	m.mu.Lock()
+	m.manifest.Files[rel] = entry
+	m.mu.Unlock()
+
+	if known && errors.Is(localErr, ErrCorrupt) {
+		return rel, outcomeRepaired, nil
+	}
+	return rel, outcomeDownloaded, nil
+}
+
+// remoteChanged compares the archive's Last-Modified and Content-Length with what we fetched.
+// Missing headers are treated as unchanged, since without them there's nothing to go on
+// and the local copy has already been verified.
+func remoteChanged(resp *http.Response, entry ManifestEntry) bool {
+	if resp.ContentLength >= 0 && resp.ContentLength != entry.Size {
+		return true
+	}
+	lm, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return entry.LastModified.IsZero() || lm.After(entry.LastModified)
+}
+
+// Sync brings the mirror up to date for sid from start through end inclusive, downloading only days
+// that are missing locally, fail verification, or have changed upstream. The manifest is rewritten
+// even if some days fail.
+func (m *Mirror) Sync(ctx context.Context, sid StationID, start, end time.Time) (SyncResult, error) {
+	var result SyncResult
+
+	if end.Before(start) {
+		return result, ErrInvalidRange
+	}
+
+	m.mu.Lock()
+	_, err := m.loadManifest()
+	m.mu.Unlock()
+	if err != nil {
+		return result, err
+	}
+
+	days := Days(start, end)
+	rels := make([]string, len(days))
+	outcomes := make([]syncOutcome, len(days))
+	errs := make([]error, len(days))
+
+	limit := m.Client.Concurrency
+	if limit < 1 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+
+	for i, day := range days {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, day time.Time) {
+			defer func() { <-sem; wg.Done() }()
+			rels[i], outcomes[i], errs[i] = m.syncDay(ctx, sid, day)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("%s: %w", day.Format(time.DateOnly), errs[i])
+			}
+		}(i, day)
+	}
+
+	wg.Wait()
+
+	for i := range days {
+		if errs[i] != nil {
+			continue
+		}
+		switch outcomes[i] {
+		case outcomeDownloaded:
+			result.Downloaded = append(result.Downloaded, rels[i])
+		case outcomeUnchanged:
+			result.Unchanged = append(result.Unchanged, rels[i])
+		case outcomeRepaired:
+			result.Repaired = append(result.Repaired, rels[i])
+		case outcomeMissing:
+			result.Missing = append(result.Missing, rels[i])
+		}
+	}
+
+	if err = m.saveManifest(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// Verify checks every file in the manifest against its checksum and by re-parsing it,
+// returning the relative paths of files that are corrupt or missing locally.
+func (m *Mirror) Verify() ([]string, error) {
+	manifest, err := m.LoadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	files := make(map[string]ManifestEntry, len(manifest.Files))
+	for k, v := range manifest.Files {
+		files[k] = v
+	}
+	m.mu.Unlock()
+
+	var bad []string
+	for rel, entry := range files {
+		if err = m.verifyLocal(rel, entry); err != nil {
+			debugPrint("verify %s: %v\n", rel, err)
+			bad = append(bad, rel)
+		}
+	}
+
+	sort.Strings(bad)
+	return bad, nil
+}
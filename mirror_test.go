@@ -0,0 +1,232 @@
+package surfrad
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeArchive struct {
+	mu      sync.Mutex
+	files   map[string][]byte
+	modTime time.Time
+	gets    int32
+	gone    bool // GETs find nothing, as when a file is removed between a HEAD and a GET
+}
+
+func (a *fakeArchive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	body, ok := a.files[r.URL.Path]
+	modTime := a.modTime
+	gone := a.gone && r.Method == http.MethodGet
+	a.mu.Unlock()
+	if !ok || gone {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method == http.MethodGet {
+		atomic.AddInt32(&a.gets, 1)
+	}
+	http.ServeContent(w, r, path.Base(r.URL.Path), modTime, bytes.NewReader(body))
+}
+
+func TestMirrorSync(t *testing.T) {
+	body, err := os.ReadFile("testdata/dra24048.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := &fakeArchive{
+		files: map[string][]byte{
+			"/Desert_Rock_NV/2024/dra24048.dat": body,
+			"/Desert_Rock_NV/2024/dra24049.dat": body,
+		},
+		modTime: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+	srv := httptest.NewServer(archive)
+	defer srv.Close()
+
+	root := t.TempDir()
+	client := &Client{BaseURL: srv.URL, HTTPClient: srv.Client(), RetryDelay: time.Millisecond, Concurrency: 2}
+	start := time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 2, 19, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	res, err := NewMirror(root, client).Sync(ctx, StationIDDesertRock, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Downloaded) != 2 || len(res.Missing) != 1 {
+		t.Fatalf("first sync: %+v", res)
+	}
+	if _, err = os.Stat(filepath.Join(root, "Desert_Rock_NV", "2024", "dra24048.dat")); err != nil {
+		t.Fatal(err)
+	}
+
+	// a fresh Mirror must pick up the manifest from disk and download nothing
+	res, err = NewMirror(root, client).Sync(ctx, StationIDDesertRock, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Unchanged) != 2 || len(res.Downloaded) != 0 || atomic.LoadInt32(&archive.gets) != 2 {
+		t.Fatalf("second sync should be a no-op: %+v (%d GETs)", res, archive.gets)
+	}
+
+	// corrupt one local file
+	local := filepath.Join(root, "Desert_Rock_NV", "2024", "dra24049.dat")
+	if err = os.WriteFile(local, body[:len(body)/2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMirror(root, client)
+	bad, err := m.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) != 1 || bad[0] != "Desert_Rock_NV/2024/dra24049.dat" {
+		t.Fatalf("Verify() == %v", bad)
+	}
+
+	res, err = m.Sync(ctx, StationIDDesertRock, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Repaired) != 1 || len(res.Unchanged) != 1 {
+		t.Fatalf("repair sync: %+v", res)
+	}
+	if bad, _ = m.Verify(); len(bad) != 0 {
+		t.Fatalf("Verify() after repair == %v", bad)
+	}
+
+	// a corrupt copy of a day gone from upstream is quarantined, not trusted again
+	if err = os.WriteFile(local, body[:len(body)/2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	archive.mu.Lock()
+	delete(archive.files, "/Desert_Rock_NV/2024/dra24049.dat")
+	archive.mu.Unlock()
+
+	res, err = m.Sync(ctx, StationIDDesertRock, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Missing) != 2 || len(res.Unchanged) != 1 {
+		t.Fatalf("quarantine sync: %+v", res)
+	}
+	if _, err = os.Stat(local + ".corrupt"); err != nil {
+		t.Errorf("expected the corrupt copy to be quarantined: %v", err)
+	}
+	if bad, _ = m.Verify(); len(bad) != 0 {
+		t.Fatalf("Verify() after quarantine == %v", bad)
+	}
+	archive.mu.Lock()
+	archive.files["/Desert_Rock_NV/2024/dra24049.dat"] = body
+	archive.mu.Unlock()
+
+	// upstream changes are picked up
+	archive.mu.Lock()
+	archive.modTime = archive.modTime.Add(24 * time.Hour)
+	archive.mu.Unlock()
+
+	res, err = m.Sync(ctx, StationIDDesertRock, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Downloaded) != 2 {
+		t.Fatalf("changed upstream sync: %+v", res)
+	}
+
+	manifest, err := m.LoadManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := manifest.Files["Desert_Rock_NV/2024/dra24048.dat"]
+	if entry.Records != 1440 || entry.SHA256 != checksum(body) || !entry.LastModified.Equal(archive.modTime) {
+		t.Errorf("unexpected manifest entry: %+v", entry)
+	}
+}
+
+func TestMirrorGoneAfterHead(t *testing.T) {
+	body, err := os.ReadFile("testdata/dra24048.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := &fakeArchive{
+		files:   map[string][]byte{"/Desert_Rock_NV/2024/dra24048.dat": body},
+		modTime: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+	srv := httptest.NewServer(archive)
+	defer srv.Close()
+
+	root := t.TempDir()
+	client := &Client{BaseURL: srv.URL, HTTPClient: srv.Client(), RetryDelay: time.Millisecond}
+	day := time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	m := NewMirror(root, client)
+	if _, err = m.Sync(ctx, StationIDDesertRock, day, day); err != nil {
+		t.Fatal(err)
+	}
+
+	// HEAD reports a change, then the file is gone by the GET
+	archive.mu.Lock()
+	archive.modTime = archive.modTime.Add(24 * time.Hour)
+	archive.gone = true
+	archive.mu.Unlock()
+
+	res, err := m.Sync(ctx, StationIDDesertRock, day, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Unchanged) != 1 || len(res.Missing) != 0 {
+		t.Fatalf("expected the verified copy to be kept: %+v", res)
+	}
+	local := filepath.Join(root, "Desert_Rock_NV", "2024", "dra24048.dat")
+	if _, err = os.Stat(local); err != nil {
+		t.Errorf("verified copy was moved: %v", err)
+	}
+	manifest, err := m.LoadManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := manifest.Files["Desert_Rock_NV/2024/dra24048.dat"]; !ok {
+		t.Error("verified copy was dropped from the manifest")
+	}
+}
+
+func TestCheckContents(t *testing.T) {
+	body, err := os.ReadFile("testdata/dra24048.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		data     []byte
+		expected int
+		wantErr  bool
+	}{
+		{"valid", body, 1440, false},
+		{"valid, unknown count", body, 0, false},
+		{"truncated", body[:len(body)/2], 1440, true},
+		{"garbage", []byte("<html>not found</html>"), 0, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := checkContents(tc.data, tc.expected)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("checkContents() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
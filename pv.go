@@ -0,0 +1,191 @@
+package surfrad
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// CellTemperatureModel estimates PV cell temperature in °C from plane-of-array irradiance in W m^-2,
+// air temperature in °C and wind speed in m/s.
+type CellTemperatureModel interface {
+	CellTemperature(poa, airTemp, windSpeed float64) float64
+}
+
+// SAPM is the Sandia Array Performance Model cell temperature model (King et al. 2004).
+type SAPM struct {
+	A      float64
+	B      float64 // s/m
+	DeltaT float64 // °C, cell to module back difference at 1000 W m^-2
+}
+
+// Parameters from King et al. (2004) for common mountings.
+var (
+	SAPMOpenRackGlassGlass    = SAPM{A: -3.47, B: -0.0594, DeltaT: 3}
+	SAPMCloseMountGlassGlass  = SAPM{A: -2.98, B: -0.0471, DeltaT: 1}
+	SAPMOpenRackGlassPolymer  = SAPM{A: -3.56, B: -0.075, DeltaT: 3}
+	SAPMInsulatedGlassPolymer = SAPM{A: -2.81, B: -0.0455, DeltaT: 0}
+)
+
+func (m SAPM) CellTemperature(poa, airTemp, windSpeed float64) float64 {
+	module := poa*math.Exp(m.A+m.B*windSpeed) + airTemp
+	return module + poa/1000*m.DeltaT
+}
+
+// Faiman is the Faiman (2008) module temperature model, as used by PVsyst and IEC 61853.
+type Faiman struct {
+	U0 float64 // W m^-2 °C^-1
+	U1 float64 // W m^-3 s °C^-1
+}
+
+// DefaultFaiman uses the IEC 61853 defaults for an open rack c-Si module.
+var DefaultFaiman = Faiman{U0: 25, U1: 6.84}
+
+func (m Faiman) CellTemperature(poa, airTemp, windSpeed float64) float64 {
+	return airTemp + poa/(m.U0+m.U1*windSpeed)
+}
+
+// PVSystem describes a PV plant for the PVWatts (Dobos 2014) DC and inverter models.
+// A nil Tracker means the array is fixed at Surface.
+type PVSystem struct {
+	Surface       Surface
+	Tracker       *SingleAxisTracker
+	Transposition TranspositionModel
+
+	CellTemperature CellTemperatureModel // defaults to DefaultFaiman
+
+	DCCapacity             float64 // W at 1000 W m^-2 and 25 °C cell temperature
+	TemperatureCoefficient float64 // fractional power change per °C, e.g. -0.004
+	Losses                 float64 // fractional DC system losses (soiling, wiring, mismatch, ...)
+
+	ACCapacity         float64 // inverter rating in W; defaults to DCCapacity
+	InverterEfficiency float64 // nominal inverter efficiency; defaults to 0.96
+}
+
+// DefaultPVSystem returns a south facing, latitude tilted array with PVWatts defaults and a
+// DC/AC ratio of 1.2.
+func DefaultPVSystem(loc Location, dcCapacity float64) PVSystem {
+	azimuth := 180.0
+	if loc.Latitude < 0 {
+		azimuth = 0
+	}
+	return PVSystem{
+		Surface:                Surface{Tilt: math.Abs(loc.Latitude), Azimuth: azimuth},
+		Transposition:          Perez,
+		CellTemperature:        DefaultFaiman,
+		DCCapacity:             dcCapacity,
+		TemperatureCoefficient: -0.0037,
+		Losses:                 0.14,
+		ACCapacity:             dcCapacity / 1.2,
+		InverterEfficiency:     0.96,
+	}
+}
+
+var ErrInvalidSystem = errors.New("PV system needs a positive DC capacity and losses below 1")
+
+// PVOutput is the simulated output of a PV system for one record. All fields but Timestamp and
+// Clipped are MissingValue when a required input was missing.
+type PVOutput struct {
+	Timestamp       time.Time `json:"timestamp"`
+	POA             float64   `json:"poa"`       // W m^-2
+	CellTemperature float64   `json:"cell_temp"` // °C
+	DC              float64   `json:"dc"`        // W
+	AC              float64   `json:"ac"`        // W
+	Clipped         bool      `json:"clipped"`
+}
+
+// pvwattsInverterReference is the reference efficiency of the PVWatts inverter curve.
+const pvwattsInverterReference = 0.9637
+
+// PVWattsDC returns DC power in W for an effective plane-of-array irradiance and cell temperature.
+func PVWattsDC(poa, cellTemp, dcCapacity, tempCoefficient float64) float64 {
+	return poa / 1000 * dcCapacity * (1 + tempCoefficient*(cellTemp-25))
+}
+
+// PVWattsAC returns AC power in W for a DC input, and whether the inverter clipped it to its rating.
+func PVWattsAC(dc, acCapacity, efficiency float64) (float64, bool) {
+	if dc <= 0 {
+		return 0, false
+	}
+
+	dcCapacity := acCapacity / efficiency
+	zeta := dc / dcCapacity
+	eta := efficiency / pvwattsInverterReference * (-0.0162*zeta - 0.0059/zeta + 0.9858)
+
+	ac := math.Max(0, eta*dc)
+	if ac > acCapacity {
+		return acCapacity, true
+	}
+	return ac, false
+}
+
+func (sys PVSystem) withDefaults() PVSystem {
+	if sys.CellTemperature == nil {
+		sys.CellTemperature = DefaultFaiman
+	}
+	if sys.ACCapacity <= 0 {
+		sys.ACCapacity = sys.DCCapacity
+	}
+	if sys.InverterEfficiency <= 0 {
+		sys.InverterEfficiency = 0.96
+	}
+	return sys
+}
+
+// simulate runs the DC and inverter models for one record given its plane-of-array irradiance.
+func (sys PVSystem) simulate(d Data, poa POA) PVOutput {
+	out := PVOutput{
+		Timestamp:       d.Timestamp,
+		POA:             MissingValue,
+		CellTemperature: MissingValue,
+		DC:              MissingValue,
+		AC:              MissingValue,
+	}
+
+	if poa.Total == MissingValue || d.temperatureMissing() ||
+		IsMissing(d.WindSpeedMetersPerSecond, d.QC.WindSpeedMetersPerSecond) {
+		return out
+	}
+
+	out.POA = poa.Total
+	out.CellTemperature = sys.CellTemperature.CellTemperature(poa.Total, d.TemperatureC, math.Max(0, d.WindSpeedMetersPerSecond))
+	out.DC = math.Max(0, PVWattsDC(poa.Total, out.CellTemperature, sys.DCCapacity, sys.TemperatureCoefficient)*(1-sys.Losses))
+	out.AC, out.Clipped = PVWattsAC(out.DC, sys.ACCapacity, sys.InverterEfficiency)
+
+	return out
+}
+
+// SimulatePV produces the expected output of sys for every entry, driven by the measured GHI, DNI and
+// DHI, air temperature and wind speed.
+func (s Station) SimulatePV(sys PVSystem) ([]PVOutput, error) {
+	if sys.DCCapacity <= 0 || sys.Losses < 0 || sys.Losses >= 1 {
+		return nil, ErrInvalidSystem
+	}
+	sys = sys.withDefaults()
+
+	var poa []POA
+	if sys.Tracker != nil {
+		poa = s.TrackerPlaneOfArray(*sys.Tracker, sys.Transposition)
+	} else {
+		poa = s.PlaneOfArray(sys.Surface, sys.Transposition)
+	}
+
+	out := make([]PVOutput, len(s.Entries))
+	for i, d := range s.Entries {
+		out[i] = sys.simulate(d, poa[i])
+	}
+
+	return out, nil
+}
+
+// ACEnergy integrates AC power into energy in Wh, assuming each output covers the given step.
+// Missing outputs are skipped.
+func ACEnergy(out []PVOutput, step time.Duration) float64 {
+	var wh float64
+	for _, o := range out {
+		if o.AC != MissingValue {
+			wh += o.AC * step.Hours()
+		}
+	}
+	return wh
+}
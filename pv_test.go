@@ -0,0 +1,111 @@
+package surfrad
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCellTemperature(t *testing.T) {
+	cases := []struct {
+		name     string
+		model    CellTemperatureModel
+		expected float64
+	}{
+		// 20 + 1000/(25+6.84)
+		{"faiman", DefaultFaiman, 51.41},
+		// 1000*exp(-3.47-0.0594)+20 + 3
+		{"sapm", SAPMOpenRackGlassGlass, 52.32},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.model.CellTemperature(1000, 20, 1)
+			if math.Abs(got-tc.expected) > 0.01 {
+				t.Errorf("CellTemperature() == %.3f, expected %.2f", got, tc.expected)
+			}
+			if tc.model.CellTemperature(1000, 20, 10) >= got {
+				t.Error("wind should cool the cell")
+			}
+			if tc.model.CellTemperature(0, 20, 1) != 20 {
+				t.Error("cell should be at air temperature in the dark")
+			}
+		})
+	}
+}
+
+func TestPVWatts(t *testing.T) {
+	if dc := PVWattsDC(1000, 25, 5000, -0.004); dc != 5000 {
+		t.Errorf("DC at STC == %v, expected 5000", dc)
+	}
+	if dc := PVWattsDC(1000, 35, 5000, -0.004); math.Abs(dc-4800) > 1e-9 {
+		t.Errorf("DC at 35 °C == %v, expected 4800", dc)
+	}
+
+	// at the nominal DC input the efficiency is the nominal one
+	if ac, clipped := PVWattsAC(4000/0.96, 4000, 0.96); math.Abs(ac-4000) > 1e-6 || clipped {
+		t.Errorf("AC at nominal input == %v (clipped %v), expected 4000", ac, clipped)
+	}
+	if ac, clipped := PVWattsAC(5000, 4000, 0.96); ac != 4000 || !clipped {
+		t.Errorf("AC == %v (clipped %v), expected clipping at 4000", ac, clipped)
+	}
+	if ac, _ := PVWattsAC(1000, 4000, 0.96); ac >= 1000 || ac < 900 {
+		t.Errorf("AC at part load == %v", ac)
+	}
+	if ac, _ := PVWattsAC(0, 4000, 0.96); ac != 0 {
+		t.Errorf("AC at night == %v", ac)
+	}
+}
+
+func TestSimulatePV(t *testing.T) {
+	station := readTestStation(t)
+
+	if _, err := station.SimulatePV(PVSystem{}); err != ErrInvalidSystem {
+		t.Errorf("expected ErrInvalidSystem, got %v", err)
+	}
+
+	fixed := DefaultPVSystem(station.LocatedAt, 5000)
+	tracked := fixed
+	tracked.Tracker = &SingleAxisTracker{AxisAzimuth: 180, MaxAngle: 60, Backtrack: true, GCR: 0.35}
+	// an undersized inverter clips around noon
+	small := fixed
+	small.ACCapacity = 500
+
+	clipping := map[string]int{}
+	for _, tc := range []struct {
+		name string
+		sys  PVSystem
+	}{{"fixed", fixed}, {"tracked", tracked}, {"small inverter", small}} {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := station.SimulatePV(tc.sys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out) != station.Len() {
+				t.Fatalf("expected %d outputs, got %d", station.Len(), len(out))
+			}
+
+			var clipped int
+			for i, o := range out {
+				if o.AC == MissingValue {
+					continue
+				}
+				if o.AC < 0 || o.AC > tc.sys.ACCapacity || o.AC > o.DC {
+					t.Errorf("%s: implausible output %+v", station.Entries[i].Timestamp, o)
+				}
+				if o.Clipped {
+					clipped++
+				}
+			}
+
+			energy := ACEnergy(out, time.Minute)
+			if energy <= 0 || energy > tc.sys.ACCapacity*24 {
+				t.Errorf("daily energy %.0f Wh is implausible", energy)
+			}
+			clipping[tc.name] = clipped
+		})
+	}
+
+	if clipping["small inverter"] <= clipping["fixed"] {
+		t.Errorf("an undersized inverter should clip more: %v", clipping)
+	}
+}
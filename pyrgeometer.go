@@ -0,0 +1,244 @@
+package surfrad
+
+import (
+	"math"
+	"time"
+)
+
+// The pyrgeometer equation of Albrecht & Cox (1977) gives longwave irradiance from the thermopile
+// signal and the case and dome temperatures (K):
+//
+//	L = U/C + σTc^4 - kσ(Td^4 - Tc^4)
+//
+// SURFRAD only reports L and the temperatures, so the thermopile term U/C is recovered by inverting the
+// equation under an assumed dome coefficient k, and can then be re-evaluated under a different one.
+
+// PyrgeometerIrradiance returns the longwave irradiance in W m^-2 for a thermopile term U/C in W m^-2,
+// case and dome temperatures in K and dome coefficient k.
+func PyrgeometerIrradiance(thermopile, caseTemp, domeTemp, k float64) float64 {
+	tc4, td4 := math.Pow(caseTemp, 4), math.Pow(domeTemp, 4)
+	return thermopile + StefanBoltzmann*tc4 - k*StefanBoltzmann*(td4-tc4)
+}
+
+// ThermopileSignal inverts PyrgeometerIrradiance, returning the thermopile term U/C in W m^-2.
+func ThermopileSignal(irradiance, caseTemp, domeTemp, k float64) float64 {
+	tc4, td4 := math.Pow(caseTemp, 4), math.Pow(domeTemp, 4)
+	return irradiance - StefanBoltzmann*tc4 + k*StefanBoltzmann*(td4-tc4)
+}
+
+// PyrgeometerOptions configures recomputation and checks of the pyrgeometer data.
+type PyrgeometerOptions struct {
+	// ReportedDomeCoefficient is the k assumed to have produced the reported irradiance.
+	ReportedDomeCoefficient float64
+	// DomeCoefficient is the k to recompute the irradiance with.
+	DomeCoefficient float64
+
+	// Dome minus case temperature differences beyond these (K) are flagged questionable and bad.
+	QuestionableDomeCase float64
+	BadDomeCase          float64
+
+	// LocalTime makes PyrgeometerHealth report local standard time days instead of UTC days.
+	LocalTime bool
+}
+
+func DefaultPyrgeometerOptions() PyrgeometerOptions {
+	return PyrgeometerOptions{
+		ReportedDomeCoefficient: 4,
+		DomeCoefficient:         4,
+		QuestionableDomeCase:    1,
+		BadDomeCase:             2,
+	}
+}
+
+func (o PyrgeometerOptions) withDefaults() PyrgeometerOptions {
+	def := DefaultPyrgeometerOptions()
+	if o.ReportedDomeCoefficient <= 0 {
+		o.ReportedDomeCoefficient = def.ReportedDomeCoefficient
+	}
+	if o.DomeCoefficient <= 0 {
+		o.DomeCoefficient = def.DomeCoefficient
+	}
+	if o.QuestionableDomeCase <= 0 {
+		o.QuestionableDomeCase = def.QuestionableDomeCase
+	}
+	if o.BadDomeCase <= 0 {
+		o.BadDomeCase = def.BadDomeCase
+	}
+	return o
+}
+
+// PyrgeometerReading is one instrument's recomputed record. Values are MissingValue when the
+// irradiance or either temperature is missing, in which case Flag is QCBad.
+type PyrgeometerReading struct {
+	Reported   float64 `json:"reported"`
+	Thermopile float64 `json:"thermopile"` // U/C, W m^-2
+	Recomputed float64 `json:"recomputed"`
+	DomeCase   float64 `json:"dome_case"` // dome minus case temperature, K
+	Flag       int     `json:"flag"`      // QCGood, QCQuestionable or QCBad from the dome-case difference
+}
+
+type PyrgeometerCheck struct {
+	Timestamp   time.Time          `json:"timestamp"`
+	Downwelling PyrgeometerReading `json:"downwelling"`
+	Upwelling   PyrgeometerReading `json:"upwelling"`
+}
+
+func (o PyrgeometerOptions) reading(irradiance float64, qc int, caseTemp float64, caseQC int, domeTemp float64, domeQC int) PyrgeometerReading {
+	if IsMissing(irradiance, qc) || IsMissing(caseTemp, caseQC) || IsMissing(domeTemp, domeQC) ||
+		caseTemp <= 0 || domeTemp <= 0 {
+		return PyrgeometerReading{
+			Reported:   MissingValue,
+			Thermopile: MissingValue,
+			Recomputed: MissingValue,
+			DomeCase:   MissingValue,
+			Flag:       QCBad,
+		}
+	}
+
+	r := PyrgeometerReading{
+		Reported:   irradiance,
+		Thermopile: ThermopileSignal(irradiance, caseTemp, domeTemp, o.ReportedDomeCoefficient),
+		DomeCase:   domeTemp - caseTemp,
+	}
+	r.Recomputed = PyrgeometerIrradiance(r.Thermopile, caseTemp, domeTemp, o.DomeCoefficient)
+
+	switch diff := math.Abs(r.DomeCase); {
+	case diff > o.BadDomeCase:
+		r.Flag = QCBad
+	case diff > o.QuestionableDomeCase:
+		r.Flag = QCQuestionable
+	}
+
+	return r
+}
+
+// CheckPyrgeometers recomputes both pyrgeometers' irradiance and flags their dome-case differences.
+func (d Data) CheckPyrgeometers(opts PyrgeometerOptions) PyrgeometerCheck {
+	opts = opts.withDefaults()
+	return PyrgeometerCheck{
+		Timestamp: d.Timestamp,
+		Downwelling: opts.reading(d.DownwellingIR, d.QC.DownwellingIR,
+			d.DownwellingIRCaseTemp, d.QC.DownwellingIRCaseTemp, d.DownwellingIRDomeTemp, d.QC.DownwellingIRDomeTemp),
+		Upwelling: opts.reading(d.UpwellingIR, d.QC.UpwellingIR,
+			d.UpwellingIRCaseTemp, d.QC.UpwellingIRCaseTemp, d.UpwellingIRDomeTemp, d.QC.UpwellingIRDomeTemp),
+	}
+}
+
+// CheckPyrgeometers runs CheckPyrgeometers for every entry.
+func (s Station) CheckPyrgeometers(opts PyrgeometerOptions) []PyrgeometerCheck {
+	out := make([]PyrgeometerCheck, len(s.Entries))
+	for i, d := range s.Entries {
+		out[i] = d.CheckPyrgeometers(opts)
+	}
+	return out
+}
+
+// PyrgeometerHealth summarizes one instrument over one day.
+type PyrgeometerHealth struct {
+	Records      int `json:"records"`
+	Missing      int `json:"missing"`
+	Questionable int `json:"questionable"`
+	Bad          int `json:"bad"` // excluding missing records
+
+	MeanDomeCase   float64 `json:"mean_dome_case"`    // K
+	MaxDomeCase    float64 `json:"max_abs_dome_case"` // largest absolute difference, K
+	CaseTempRange  float64 `json:"case_temp_range"`   // K, zero suggests a stuck thermistor
+	MeanCorrection float64 `json:"mean_correction"`   // mean recomputed minus reported, W m^-2
+}
+
+// Availability is the fraction of records that weren't missing.
+func (h PyrgeometerHealth) Availability() float64 {
+	if h.Records == 0 {
+		return 0
+	}
+	return float64(h.Records-h.Missing) / float64(h.Records)
+}
+
+type DailyPyrgeometerHealth struct {
+	Day         time.Time         `json:"day"`
+	Downwelling PyrgeometerHealth `json:"downwelling"`
+	Upwelling   PyrgeometerHealth `json:"upwelling"`
+}
+
+type healthAccumulator struct {
+	h                PyrgeometerHealth
+	minCase, maxCase float64
+	sumDiff, sumCorr float64
+	valid            int
+}
+
+func newHealthAccumulator() *healthAccumulator {
+	return &healthAccumulator{minCase: math.Inf(1), maxCase: math.Inf(-1)}
+}
+
+func (a *healthAccumulator) add(r PyrgeometerReading, caseTemp float64) {
+	a.h.Records++
+	if r.Reported == MissingValue {
+		a.h.Missing++
+		return
+	}
+
+	switch r.Flag {
+	case QCQuestionable:
+		a.h.Questionable++
+	case QCBad:
+		a.h.Bad++
+	}
+
+	a.valid++
+	a.sumDiff += r.DomeCase
+	a.sumCorr += r.Recomputed - r.Reported
+	a.h.MaxDomeCase = math.Max(a.h.MaxDomeCase, math.Abs(r.DomeCase))
+	a.minCase = math.Min(a.minCase, caseTemp)
+	a.maxCase = math.Max(a.maxCase, caseTemp)
+}
+
+func (a *healthAccumulator) health() PyrgeometerHealth {
+	h := a.h
+	if a.valid == 0 {
+		h.MeanDomeCase, h.MaxDomeCase, h.CaseTempRange, h.MeanCorrection = MissingValue, MissingValue, MissingValue, MissingValue
+		return h
+	}
+	h.MeanDomeCase = a.sumDiff / float64(a.valid)
+	h.MeanCorrection = a.sumCorr / float64(a.valid)
+	h.CaseTempRange = a.maxCase - a.minCase
+	return h
+}
+
+// PyrgeometerHealth reports per day sensor health indicators for both pyrgeometers, in order of
+// first appearance. Days are UTC unless opts.LocalTime is set.
+func (s Station) PyrgeometerHealth(opts PyrgeometerOptions) []DailyPyrgeometerHealth {
+	checks := s.CheckPyrgeometers(opts)
+
+	tz := time.UTC
+	if opts.LocalTime {
+		tz = s.TimeZone()
+	}
+
+	type day struct {
+		start    time.Time
+		down, up *healthAccumulator
+	}
+	var (
+		days  []*day
+		index = make(map[time.Time]*day)
+	)
+
+	for i, c := range checks {
+		start := LocalDay(s.Entries[i].Timestamp, tz)
+		dd, ok := index[start]
+		if !ok {
+			dd = &day{start: start, down: newHealthAccumulator(), up: newHealthAccumulator()}
+			index[start] = dd
+			days = append(days, dd)
+		}
+		dd.down.add(c.Downwelling, s.Entries[i].DownwellingIRCaseTemp)
+		dd.up.add(c.Upwelling, s.Entries[i].UpwellingIRCaseTemp)
+	}
+
+	out := make([]DailyPyrgeometerHealth, len(days))
+	for i, dd := range days {
+		out[i] = DailyPyrgeometerHealth{Day: dd.start, Downwelling: dd.down.health(), Upwelling: dd.up.health()}
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package surfrad
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPyrgeometerEquation(t *testing.T) {
+	// with dome and case at the same temperature the dome term vanishes
+	if got := PyrgeometerIrradiance(-100, 290, 290, 4); math.Abs(got-(StefanBoltzmann*math.Pow(290, 4)-100)) > 1e-9 {
+		t.Errorf("PyrgeometerIrradiance() == %v", got)
+	}
+
+	// a warmer dome emits more towards the thermopile, which the equation subtracts
+	if PyrgeometerIrradiance(-100, 290, 291, 4) >= PyrgeometerIrradiance(-100, 290, 290, 4) {
+		t.Error("a warm dome should lower the corrected irradiance")
+	}
+
+	l := PyrgeometerIrradiance(-80, 285, 284.5, 3.5)
+	if got := ThermopileSignal(l, 285, 284.5, 3.5); math.Abs(got+80) > 1e-9 {
+		t.Errorf("ThermopileSignal() == %v, expected -80", got)
+	}
+}
+
+func TestCheckPyrgeometers(t *testing.T) {
+	d := Data{
+		DownwellingIR: 300, DownwellingIRCaseTemp: 290, DownwellingIRDomeTemp: 290.5,
+		UpwellingIR: 400, UpwellingIRCaseTemp: 295, UpwellingIRDomeTemp: 296.5,
+	}
+
+	opts := DefaultPyrgeometerOptions()
+	c := d.CheckPyrgeometers(opts)
+	if c.Downwelling.Flag != QCGood || c.Upwelling.Flag != QCQuestionable {
+		t.Errorf("unexpected flags: %+v", c)
+	}
+	if math.Abs(c.Downwelling.Recomputed-300) > 1e-9 {
+		t.Errorf("recomputing with the same coefficient should round trip, got %v", c.Downwelling.Recomputed)
+	}
+
+	opts.DomeCoefficient = 3
+	c = d.CheckPyrgeometers(opts)
+	// a smaller coefficient corrects less for the warm dome
+	expected := 300 + StefanBoltzmann*(math.Pow(290.5, 4)-math.Pow(290, 4))
+	if math.Abs(c.Downwelling.Recomputed-expected) > 1e-9 {
+		t.Errorf("recomputed == %v, expected %v", c.Downwelling.Recomputed, expected)
+	}
+
+	d.UpwellingIRDomeTemp = 298
+	d.DownwellingIRCaseTemp, d.QC.DownwellingIRCaseTemp = 0, QCMissing
+	c = d.CheckPyrgeometers(opts)
+	if c.Upwelling.Flag != QCBad {
+		t.Errorf("expected a bad dome-case difference, got %+v", c.Upwelling)
+	}
+	if c.Downwelling.Recomputed != MissingValue || c.Downwelling.Flag != QCBad {
+		t.Errorf("expected a missing reading, got %+v", c.Downwelling)
+	}
+}
+
+func TestPyrgeometerHealth(t *testing.T) {
+	station := readTestStation(t)
+
+	health := station.PyrgeometerHealth(DefaultPyrgeometerOptions())
+	if len(health) != 1 || !health[0].Day.Equal(time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected one day, got %+v", health)
+	}
+
+	for name, h := range map[string]PyrgeometerHealth{"downwelling": health[0].Downwelling, "upwelling": health[0].Upwelling} {
+		if h.Records != station.Len() {
+			t.Errorf("%s: %d records", name, h.Records)
+		}
+		if h.Availability() < 0.99 || h.Bad > 0 || h.Questionable > 0 {
+			t.Errorf("%s: expected a healthy instrument: %+v", name, h)
+		}
+		if h.MaxDomeCase > 1 || h.CaseTempRange <= 0 || math.Abs(h.MeanCorrection) > 1e-9 {
+			t.Errorf("%s: unexpected indicators: %+v", name, h)
+		}
+	}
+}
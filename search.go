@@ -0,0 +1,73 @@
+package surfrad
+
+import (
+	"sort"
+	"time"
+)
+
+// Sorted reports whether the entries are in non-decreasing time order. ReadData and Merge always
+// return sorted entries; the lookups below rely on it.
+func (s Station) Sorted() bool {
+	return sort.SliceIsSorted(s.Entries, func(a, b int) bool {
+		return s.Entries[a].Timestamp.Before(s.Entries[b].Timestamp)
+	})
+}
+
+// Sort puts the entries in time order, keeping the order of entries that share a timestamp.
+//
+//goland:noinspection GoMixedReceiverTypes
+func (s *Station) Sort() {
+	sort.SliceStable(s.Entries, func(a, b int) bool {
+		return s.Entries[a].Timestamp.Before(s.Entries[b].Timestamp)
+	})
+}
+
+// Search returns the index of the first entry at or after t, or Len if there is none.
+func (s Station) Search(t time.Time) int {
+	return sort.Search(len(s.Entries), func(i int) bool {
+		return !s.Entries[i].Timestamp.Before(t)
+	})
+}
+
+// Between returns the entries at or after start and before end. A zero start or end leaves that side
+// open. The result shares the backing array of s.Entries.
+func (s Station) Between(start, end time.Time) []Data {
+	lo, hi := 0, len(s.Entries)
+	if !start.IsZero() {
+		lo = s.Search(start)
+	}
+	if !end.IsZero() {
+		hi = s.Search(end)
+	}
+	if hi < lo {
+		hi = lo
+	}
+	return s.Entries[lo:hi]
+}
+
+// At returns the entry with timestamp t.
+func (s Station) At(t time.Time) (Data, bool) {
+	if i := s.Search(t); i < len(s.Entries) && s.Entries[i].Timestamp.Equal(t) {
+		return s.Entries[i], true
+	}
+	return Data{}, false
+}
+
+// Nearest returns the entry closest in time to t, the earlier one on a tie. It's false only when there
+// are no entries.
+func (s Station) Nearest(t time.Time) (Data, bool) {
+	if len(s.Entries) == 0 {
+		return Data{}, false
+	}
+	i := s.Search(t)
+	switch {
+	case i == 0:
+		return s.Entries[0], true
+	case i == len(s.Entries):
+		return s.Entries[i-1], true
+	}
+	if t.Sub(s.Entries[i-1].Timestamp) <= s.Entries[i].Timestamp.Sub(t) {
+		return s.Entries[i-1], true
+	}
+	return s.Entries[i], true
+}
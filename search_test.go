@@ -0,0 +1,109 @@
+package surfrad
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStationLookups(t *testing.T) {
+	st := readTestStation(t)
+	if !st.Sorted() {
+		t.Fatal("test data should be sorted")
+	}
+
+	first := st.Entries[0].Timestamp
+	at := func(minutes int) time.Time { return first.Add(time.Duration(minutes) * time.Minute) }
+
+	t.Run("between", func(t *testing.T) {
+		cases := []struct {
+			name       string
+			start, end time.Time
+			first, n   int
+		}{
+			{"hour", at(60), at(120), 60, 60},
+			{"open start", time.Time{}, at(10), 0, 10},
+			{"open end", at(1430), time.Time{}, 1430, 10},
+			{"both open", time.Time{}, time.Time{}, 0, 1440},
+			{"off grid", at(60).Add(30 * time.Second), at(62).Add(time.Second), 61, 2},
+			{"reversed", at(120), at(60), 120, 0},
+			{"before data", first.Add(-time.Hour), first, 0, 0},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				got := st.Between(tc.start, tc.end)
+				if len(got) != tc.n {
+					t.Fatalf("got %d entries, expected %d", len(got), tc.n)
+				}
+				if tc.n > 0 && !got[0].Timestamp.Equal(st.Entries[tc.first].Timestamp) {
+					t.Errorf("first entry at %v, expected %v", got[0].Timestamp, st.Entries[tc.first].Timestamp)
+				}
+			})
+		}
+	})
+
+	t.Run("at", func(t *testing.T) {
+		if d, ok := st.At(at(600)); !ok || d != st.Entries[600] {
+			t.Errorf("At(%v) == %v, %t", at(600), d.Timestamp, ok)
+		}
+		if _, ok := st.At(at(600).Add(time.Second)); ok {
+			t.Error("expected no entry between minutes")
+		}
+		if _, ok := st.At(at(2000)); ok {
+			t.Error("expected no entry after the data")
+		}
+	})
+
+	t.Run("nearest", func(t *testing.T) {
+		cases := []struct {
+			t      time.Time
+			expect int
+		}{
+			{at(600), 600},
+			{at(600).Add(20 * time.Second), 600},
+			{at(600).Add(40 * time.Second), 601},
+			{at(600).Add(30 * time.Second), 600},
+			{first.Add(-time.Hour), 0},
+			{at(5000), 1439},
+		}
+		for _, tc := range cases {
+			if d, ok := st.Nearest(tc.t); !ok || d != st.Entries[tc.expect] {
+				t.Errorf("Nearest(%v) == %v, expected %v", tc.t, d.Timestamp, st.Entries[tc.expect].Timestamp)
+			}
+		}
+		if _, ok := (Station{}).Nearest(first); ok {
+			t.Error("expected nothing near in an empty station")
+		}
+	})
+}
+
+func TestReadDataSorts(t *testing.T) {
+	raw, err := os.ReadFile("testdata/dra24048.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	records := lines[2:]
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+
+	st, err := ReadData(bytes.NewBufferString(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Sorted() || st.Len() != 1440 {
+		t.Errorf("expected 1440 sorted entries, got %d sorted: %t", st.Len(), st.Sorted())
+	}
+
+	st.Entries[0], st.Entries[1] = st.Entries[1], st.Entries[0]
+	if st.Sorted() {
+		t.Error("swapped entries should not be sorted")
+	}
+	st.Sort()
+	if !st.Sorted() {
+		t.Error("Sort should sort")
+	}
+}
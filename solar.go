@@ -0,0 +1,182 @@
+package surfrad
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// DefaultSolarConstant is the total solar irradiance at 1 AU in W m^-2 (Kopp & Lean 2011).
+	DefaultSolarConstant = 1361.0
+
+	degToRad = math.Pi / 180
+	radToDeg = 180 / math.Pi
+)
+
+// SolarPosition describes where the sun is for an observer at a given time, in degrees unless noted.
+type SolarPosition struct {
+	Zenith    float64 `json:"zenith"`
+	Elevation float64 `json:"elevation"`
+
+	// ApparentZenith includes atmospheric refraction, which lifts the sun by about half a degree at the horizon.
+	ApparentZenith float64 `json:"apparent_zenith"`
+
+	Azimuth     float64 `json:"azimuth"` // clockwise from north
+	Declination float64 `json:"declination"`
+	HourAngle   float64 `json:"hour_angle"`
+
+	EquationOfTime   float64 `json:"equation_of_time"`   // minutes
+	EarthSunDistance float64 `json:"earth_sun_distance"` // AU
+}
+
+// julianCentury returns Julian centuries since J2000.0 for t.
+func julianCentury(t time.Time) float64 {
+	jd := float64(t.UnixNano())/float64(24*time.Hour) + 2440587.5
+	return (jd - 2451545) / 36525
+}
+
+func mod360(x float64) float64 {
+	x = math.Mod(x, 360)
+	if x < 0 {
+		x += 360
+	}
+	return x
+}
+
+// solarGeometry implements the NOAA solar calculator (after Meeus), good to about 0.01° for 1800-2100.
+// It returns declination, equation of time and earth-sun distance for t.
+func solarGeometry(t time.Time) (declination, eqTime, distance float64) {
+	jc := julianCentury(t)
+
+	meanLong := mod360(280.46646 + jc*(36000.76983+jc*0.0003032))
+	meanAnom := 357.52911 + jc*(35999.05029-0.0001537*jc)
+	eccent := 0.016708634 - jc*(0.000042037+0.0000001267*jc)
+
+	m := meanAnom * degToRad
+	center := math.Sin(m)*(1.914602-jc*(0.004817+0.000014*jc)) +
+		math.Sin(2*m)*(0.019993-0.000101*jc) +
+		math.Sin(3*m)*0.000289
+
+	trueLong := meanLong + center
+	trueAnom := meanAnom + center
+	omega := (125.04 - 1934.136*jc) * degToRad
+	appLong := trueLong - 0.00569 - 0.00478*math.Sin(omega)
+
+	meanObliq := 23 + (26+(21.448-jc*(46.815+jc*(0.00059-jc*0.001813)))/60)/60
+	obliq := (meanObliq + 0.00256*math.Cos(omega)) * degToRad
+
+	declination = math.Asin(math.Sin(obliq)*math.Sin(appLong*degToRad)) * radToDeg
+
+	y := math.Pow(math.Tan(obliq/2), 2)
+	l0 := meanLong * degToRad
+	eqTime = 4 * radToDeg * (y*math.Sin(2*l0) -
+		2*eccent*math.Sin(m) +
+		4*eccent*y*math.Sin(m)*math.Cos(2*l0) -
+		0.5*y*y*math.Sin(4*l0) -
+		1.25*eccent*eccent*math.Sin(2*m))
+
+	distance = 1.000001018 * (1 - eccent*eccent) / (1 + eccent*math.Cos(trueAnom*degToRad))
+
+	return declination, eqTime, distance
+}
+
+// EquationOfTime returns apparent minus mean solar time in minutes.
+func EquationOfTime(t time.Time) float64 {
+	_, eqTime, _ := solarGeometry(t)
+	return eqTime
+}
+
+// SunPosition computes the solar position for an observer at latitude and longitude
+// (decimal degrees, east positive). Atmospheric refraction is not applied.
+func SunPosition(t time.Time, latitude, longitude float64) SolarPosition {
+	decl, eqTime, distance := solarGeometry(t)
+
+	t = t.UTC()
+	minutes := float64(t.Hour()*60+t.Minute()) + (float64(t.Second())+float64(t.Nanosecond())/1e9)/60
+	trueSolarTime := math.Mod(minutes+eqTime+4*longitude, 1440)
+	if trueSolarTime < 0 {
+		trueSolarTime += 1440
+	}
+
+	hourAngle := trueSolarTime/4 - 180
+
+	lat := latitude * degToRad
+	dec := decl * degToRad
+	ha := hourAngle * degToRad
+
+	cosZen := math.Sin(lat)*math.Sin(dec) + math.Cos(lat)*math.Cos(dec)*math.Cos(ha)
+	zenith := math.Acos(math.Max(-1, math.Min(1, cosZen)))
+
+	var azimuth float64
+	if denom := math.Cos(lat) * math.Sin(zenith); math.Abs(denom) > 1e-9 {
+		cosAz := (math.Sin(lat)*math.Cos(zenith) - math.Sin(dec)) / denom
+		az := math.Acos(math.Max(-1, math.Min(1, cosAz))) * radToDeg
+		if hourAngle > 0 {
+			azimuth = mod360(az + 180)
+		} else {
+			azimuth = mod360(540 - az)
+		}
+	} else if latitude > 0 {
+		azimuth = 180
+	}
+
+	elevation := 90 - zenith*radToDeg
+
+	return SolarPosition{
+		Zenith:           zenith * radToDeg,
+		Elevation:        elevation,
+		ApparentZenith:   90 - elevation - refraction(elevation),
+		Azimuth:          azimuth,
+		Declination:      decl,
+		HourAngle:        hourAngle,
+		EquationOfTime:   eqTime,
+		EarthSunDistance: distance,
+	}
+}
+
+// refraction returns the approximate atmospheric refraction correction in degrees for a true solar
+// elevation, as used by the NOAA solar calculator.
+func refraction(elevation float64) float64 {
+	var arcsec float64
+	te := math.Tan(elevation * degToRad)
+	switch {
+	case elevation > 85:
+		return 0
+	case elevation > 5:
+		arcsec = 58.1/te - 0.07/math.Pow(te, 3) + 0.000086/math.Pow(te, 5)
+	case elevation > -0.575:
+		arcsec = 1735 + elevation*(-518.2+elevation*(103.4+elevation*(-12.79+elevation*0.711)))
+	default:
+		arcsec = -20.772 / te
+	}
+	return arcsec / 3600
+}
+
+// SunPosition computes the solar position at t for the station's location.
+func (l Location) SunPosition(t time.Time) SolarPosition {
+	return SunPosition(t, l.Latitude, l.Longitude)
+}
+
+// ExtraterrestrialIrradiance returns the normal incidence irradiance at the top of the atmosphere
+// in W m^-2, scaling solarConstant by the earth-sun distance at t.
+func ExtraterrestrialIrradiance(t time.Time, solarConstant float64) float64 {
+	_, _, distance := solarGeometry(t)
+	return solarConstant / (distance * distance)
+}
+
+// RelativeAirmass returns the relative optical air mass for a zenith angle in degrees (Kasten & Young 1989),
+// or MissingValue when the sun is below the horizon.
+func RelativeAirmass(zenith float64) float64 {
+	if zenith >= 90 {
+		return MissingValue
+	}
+	return 1 / (math.Cos(zenith*degToRad) + 0.50572*math.Pow(96.07995-zenith, -1.6364))
+}
+
+// AbsoluteAirmass corrects the relative airmass for station pressure in hPa.
+func AbsoluteAirmass(relative, pressure float64) float64 {
+	if relative == MissingValue {
+		return MissingValue
+	}
+	return relative * pressure / 1013.25
+}
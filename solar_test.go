@@ -0,0 +1,67 @@
+package surfrad
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSunPositionMatchesFile(t *testing.T) {
+	station := readTestStation(t)
+
+	for _, e := range station.Entries {
+		if e.SolarZenithAngle > 80 {
+			continue
+		}
+		// SURFRAD reports the apparent zenith for the middle of each one minute average
+		pos := station.LocatedAt.SunPosition(e.Timestamp.Add(-30 * time.Second))
+		if diff := math.Abs(pos.ApparentZenith - e.SolarZenithAngle); diff > 0.02 {
+			t.Fatalf("%s: zenith %.3f, file says %.3f", e.Timestamp, pos.ApparentZenith, e.SolarZenithAngle)
+		}
+	}
+}
+
+func TestSolarGeometry(t *testing.T) {
+	cases := []struct {
+		name     string
+		t        time.Time
+		eqTime   float64
+		decl     float64
+		distance float64
+	}{
+		{"perihelion", time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC), -4.5, -22.8, 0.9833},
+		{"february minimum", time.Date(2024, 2, 11, 12, 0, 0, 0, time.UTC), -14.2, -14.2, 0.9871},
+		{"june solstice", time.Date(2024, 6, 20, 20, 51, 0, 0, time.UTC), -1.6, 23.44, 1.0163},
+		{"november maximum", time.Date(2024, 11, 3, 12, 0, 0, 0, time.UTC), 16.4, -15.3, 0.9921},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pos := SunPosition(tc.t, 0, 0)
+			if math.Abs(pos.EquationOfTime-tc.eqTime) > 0.3 {
+				t.Errorf("equation of time %.2f, expected %.2f", pos.EquationOfTime, tc.eqTime)
+			}
+			if math.Abs(pos.Declination-tc.decl) > 0.2 {
+				t.Errorf("declination %.2f, expected %.2f", pos.Declination, tc.decl)
+			}
+			if math.Abs(pos.EarthSunDistance-tc.distance) > 0.001 {
+				t.Errorf("distance %.4f, expected %.4f", pos.EarthSunDistance, tc.distance)
+			}
+		})
+	}
+}
+
+func TestAirmass(t *testing.T) {
+	if am := RelativeAirmass(0); math.Abs(am-1) > 0.001 {
+		t.Errorf("RelativeAirmass(0) == %v, expected 1", am)
+	}
+	if am := RelativeAirmass(60); math.Abs(am-1.995) > 0.005 {
+		t.Errorf("RelativeAirmass(60) == %v, expected ~2", am)
+	}
+	if am := RelativeAirmass(95); am != MissingValue {
+		t.Errorf("RelativeAirmass(95) == %v, expected MissingValue", am)
+	}
+	if am := AbsoluteAirmass(2, 506.625); math.Abs(am-1) > 1e-9 {
+		t.Errorf("AbsoluteAirmass(2, 506.625) == %v, expected 1", am)
+	}
+}
@@ -1,5 +1,11 @@
 package surfrad
 
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
 /*
 "bon" is the station identifier for Bondville, Illinois
 "fpk" is the station identifier for Fort Peck, Montana
@@ -68,6 +74,26 @@ var (
 		StationPennState:     StationIDPennState,
 		StationSiouxFalls:    StationIDSiouxFalls,
 	}
+	// StationIDToDirectory maps stations to their directory in the NOAA SURFRAD archive.
+	StationIDToDirectory = map[StationID]string{
+		StationIDBondville:     "Bondville_IL",
+		StationIDFortPeck:      "Fort_Peck_MT",
+		StationIDGoodwinCreek:  "Goodwin_Creek_MS",
+		StationIDTableMountain: "Table_Mountain_CO",
+		StationIDDesertRock:    "Desert_Rock_NV",
+		StationIDPennState:     "Penn_State_PA",
+		StationIDSiouxFalls:    "Sioux_Falls_SD",
+	}
+	// StationIDToTimeZone maps stations to their local standard time. SURFRAD doesn't observe daylight saving.
+	StationIDToTimeZone = map[StationID]*time.Location{
+		StationIDBondville:     time.FixedZone("CST", -6*3600),
+		StationIDFortPeck:      time.FixedZone("MST", -7*3600),
+		StationIDGoodwinCreek:  time.FixedZone("CST", -6*3600),
+		StationIDTableMountain: time.FixedZone("MST", -7*3600),
+		StationIDDesertRock:    time.FixedZone("PST", -8*3600),
+		StationIDPennState:     time.FixedZone("EST", -5*3600),
+		StationIDSiouxFalls:    time.FixedZone("CST", -6*3600),
+	}
 )
 
 func ValidateStationID(sid StationID) bool {
@@ -89,3 +115,25 @@ func GetStationName(sid StationID) (StationName, bool) {
 	sn, ok := StationIDToName[sid]
 	return sn, ok
 }
+
+func GetStationDirectory(sid StationID) (string, bool) {
+	dir, ok := StationIDToDirectory[sid]
+	return dir, ok
+}
+
+func GetStationTimeZone(sid StationID) (*time.Location, bool) {
+	tz, ok := StationIDToTimeZone[sid]
+	return tz, ok
+}
+
+// TimeZone returns the station's local standard time zone. Unknown stations get the nautical
+// time zone of their longitude.
+func (s Station) TimeZone() *time.Location {
+	if sid, ok := GetStationID(s.StationName); ok {
+		if tz, ok := GetStationTimeZone(sid); ok {
+			return tz
+		}
+	}
+	offset := int(math.Round(s.LocatedAt.Longitude / 15))
+	return time.FixedZone(fmt.Sprintf("UTC%+d", offset), offset*3600)
+}
@@ -2,6 +2,7 @@ package surfrad
 
 import (
 	"testing"
+	"time"
 )
 
 func TestValidateStationID(t *testing.T) {
@@ -93,3 +94,35 @@ func TestGetStationName(t *testing.T) {
 		})
 	}
 }
+
+func TestGetStationDirectory(t *testing.T) {
+	for sid := range StationIDToName {
+		if dir, ok := GetStationDirectory(sid); !ok || dir == "" {
+			t.Errorf("GetStationDirectory(%q) has no directory", sid)
+		}
+	}
+	if _, ok := GetStationDirectory(StationID{'x', 'y', 'z'}); ok {
+		t.Error("GetStationDirectory for an invalid station should fail")
+	}
+}
+
+func TestStationTimeZone(t *testing.T) {
+	cases := []struct {
+		name     string
+		station  Station
+		expected int
+	}{
+		{"Desert Rock", Station{StationName: StationDesertRock}, -8 * 3600},
+		{"Penn State", Station{StationName: StationPennState}, -5 * 3600},
+		{"unknown station", Station{StationName: "Nowhere", LocatedAt: Location{Longitude: 139.7}}, 9 * 3600},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, offset := time.Date(2024, 7, 1, 0, 0, 0, 0, tc.station.TimeZone()).Zone()
+			if offset != tc.expected {
+				t.Errorf("TimeZone() offset == %d, expected %d", offset, tc.expected)
+			}
+		})
+	}
+}
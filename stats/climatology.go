@@ -0,0 +1,464 @@
+package stats
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+// climatologyVersion changes whenever the encoded Climatology would no longer decode correctly.
+const climatologyVersion = 2
+
+// maxDayWindow pools each day of the 365-day year with every other exactly once.
+const maxDayWindow = 182
+
+type ClimatologyOptions struct {
+	// Variables to include by name; empty means every measured variable.
+	Variables []string
+	// GoodQC uses only values flagged good.
+	GoodQC bool
+	// LocalTime bins hours and days in the station's local standard time instead of UTC.
+	LocalTime bool
+	// Percentiles bound the bands, between 0 and 100.
+	Percentiles []float64
+	// DayWindow pools this many days either side into each day of year; zero pools none, and at most
+	// 182 pools the whole year.
+	DayWindow int
+	// MinCoverage is the share of an hour's records that must be valid for its mean to count, up to 1.
+	MinCoverage float64
+}
+
+func DefaultClimatologyOptions() ClimatologyOptions {
+	return ClimatologyOptions{
+		Percentiles: []float64{10, 25, 75, 90},
+		DayWindow:   7,
+		MinCoverage: 0.5,
+	}
+}
+
+func (o ClimatologyOptions) withDefaults() ClimatologyOptions {
+	def := DefaultClimatologyOptions()
+	if o.Percentiles == nil {
+		o.Percentiles = def.Percentiles
+	}
+	if o.DayWindow < 0 {
+		o.DayWindow = def.DayWindow
+	}
+	if o.MinCoverage <= 0 {
+		o.MinCoverage = def.MinCoverage
+	}
+	return o
+}
+
+// Band describes the hourly means that fell in one month or day of year and hour of day.
+type Band struct {
+	N           int       `json:"n"`
+	Mean        float64   `json:"mean"`
+	StdDev      float64   `json:"std_dev"`
+	Percentiles []float64 `json:"percentiles,omitempty"` // at Climatology.Percentiles
+}
+
+type VariableClimatology struct {
+	Name      string        `json:"name"`
+	Unit      string        `json:"unit"`
+	Monthly   [12][24]Band  `json:"monthly"`     // by month, January first, and hour of day
+	DayOfYear [365][24]Band `json:"day_of_year"` // by day of a 365-day year, from 1 January, and hour of day
+}
+
+// Climatology holds the typical hourly means of a station's variables, by month and by day of year.
+// Bands are built from the mean of every hour with enough valid records.
+type Climatology struct {
+	Version     int                 `json:"version"`
+	Station     surfrad.StationName `json:"station"`
+	Start       time.Time           `json:"start"`
+	End         time.Time           `json:"end"`
+	Days        int                 `json:"days"` // days with at least one hourly mean
+	Step        time.Duration       `json:"step"`
+	GoodQC      bool                `json:"good_qc"`
+	LocalTime   bool                `json:"local_time"`
+	Percentiles []float64           `json:"percentiles"`
+	DayWindow   int                 `json:"day_window"`
+	MinCoverage float64             `json:"min_coverage"`
+
+	Variables []VariableClimatology `json:"variables"`
+}
+
+type accumulator struct {
+	sum float64
+	n   int
+}
+
+// hourly accumulates a station's values into hourly sums, keyed by day number and hour.
+type hourly struct {
+	vars  []surfrad.Variable
+	good  bool
+	local bool
+	tz    *time.Location
+	sums  []map[int64]*accumulator // indexed like vars
+}
+
+func newHourly(vars []surfrad.Variable, good, local bool) *hourly {
+	h := &hourly{vars: vars, good: good, local: local, sums: make([]map[int64]*accumulator, len(vars))}
+	for i := range h.sums {
+		h.sums[i] = make(map[int64]*accumulator)
+	}
+	return h
+}
+
+// hourKey returns the day number, days since 1970-01-01 in the binning zone, times 24 plus the hour.
+func hourKey(t time.Time, tz *time.Location) int64 {
+	t = t.In(tz)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+	return day*24 + int64(t.Hour())
+}
+
+// dayOfYear returns the zero based day of t in a 365-day year, folding 29 February onto the 28th so
+// that later dates line up across leap and common years.
+func dayOfYear(t time.Time) int {
+	doy := t.YearDay() - 1
+	if leap := time.Date(t.Year(), 12, 31, 0, 0, 0, 0, time.UTC).YearDay() == 366; leap && doy >= 59 {
+		doy--
+	}
+	return doy
+}
+
+func keyTime(key int64, tz *time.Location) time.Time {
+	day := time.Unix(key/24*86400, 0).UTC()
+	return time.Date(day.Year(), day.Month(), day.Day(), int(key%24), 0, 0, 0, tz)
+}
+
+func (h *hourly) add(st surfrad.Station) {
+	if h.tz == nil {
+		h.tz = time.UTC
+		if h.local {
+			h.tz = st.TimeZone()
+		}
+	}
+	for i := range st.Entries {
+		d := &st.Entries[i]
+		// records are binned by the middle of their averaging minute
+		key := hourKey(d.Timestamp.Add(-30*time.Second), h.tz)
+		for j, v := range h.vars {
+			qc := surfrad.QCGood
+			if v.QC != nil {
+				qc = v.QC(d)
+			}
+			value := v.Value(d)
+			if surfrad.IsMissing(value, qc) || (h.good && qc != surfrad.QCGood) {
+				continue
+			}
+			acc, ok := h.sums[j][key]
+			if !ok {
+				acc = &accumulator{}
+				h.sums[j][key] = acc
+			}
+			acc.sum += value
+			acc.n++
+		}
+	}
+}
+
+// means returns the hourly means of variable j with at least min values, sorted by key.
+func (h *hourly) means(j, min int) (keys []int64, means []float64) {
+	for key, acc := range h.sums[j] {
+		if acc.n >= min {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(a, b int) bool { return keys[a] < keys[b] })
+	means = make([]float64, len(keys))
+	for i, key := range keys {
+		acc := h.sums[j][key]
+		means[i] = acc.sum / float64(acc.n)
+	}
+	return keys, means
+}
+
+// ClimatologyBuilder accumulates station data, such as a multi-year archive read one daily file at a
+// time, into a Climatology.
+type ClimatologyBuilder struct {
+	opts    ClimatologyOptions
+	station surfrad.StationName
+	step    time.Duration
+	start   time.Time
+	end     time.Time
+	h       *hourly
+}
+
+func NewClimatologyBuilder(opts ClimatologyOptions) (*ClimatologyBuilder, error) {
+	opts = opts.withDefaults()
+	vars := surfrad.MeasuredVariables()
+	if len(opts.Variables) > 0 {
+		var err error
+		if vars, err = surfrad.SelectVariables(opts.Variables); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range opts.Percentiles {
+		if p < 0 || p > 100 {
+			return nil, fmt.Errorf("percentile %v out of range", p)
+		}
+	}
+	if opts.DayWindow > maxDayWindow {
+		return nil, fmt.Errorf("day window %d out of range, at most %d", opts.DayWindow, maxDayWindow)
+	}
+	if opts.MinCoverage > 1 {
+		return nil, fmt.Errorf("minimum coverage %v out of range", opts.MinCoverage)
+	}
+	return &ClimatologyBuilder{opts: opts, h: newHourly(vars, opts.GoodQC, opts.LocalTime)}, nil
+}
+
+// Add accumulates a station's entries. Every station added must be the same one.
+func (b *ClimatologyBuilder) Add(st surfrad.Station) error {
+	if st.Len() == 0 {
+		return nil
+	}
+	if b.station == "" {
+		b.station = st.StationName
+		b.step = st.SamplingInterval()
+	} else if st.StationName != b.station {
+		return fmt.Errorf("%w: %q, expected %q", surfrad.ErrStationMismatch, st.StationName, b.station)
+	}
+
+	for _, d := range st.Entries {
+		if b.start.IsZero() || d.Timestamp.Before(b.start) {
+			b.start = d.Timestamp
+		}
+		if d.Timestamp.After(b.end) {
+			b.end = d.Timestamp
+		}
+	}
+	b.h.add(st)
+	return nil
+}
+
+func (b *ClimatologyBuilder) minSamples() int {
+	step := b.step
+	if step <= 0 {
+		step = time.Minute
+	}
+	return max(1, int(math.Ceil(b.opts.MinCoverage*float64(time.Hour/step))))
+}
+
+func band(xs []float64, percentiles []float64) Band {
+	if len(xs) == 0 {
+		return Band{}
+	}
+	b := Band{N: len(xs), Mean: Mean(xs)}
+	if len(xs) > 1 {
+		b.StdDev = StdDev(xs)
+	}
+	sort.Float64s(xs)
+	for _, p := range percentiles {
+		b.Percentiles = append(b.Percentiles, Quantile(xs, p))
+	}
+	return b
+}
+
+// Build computes the climatology of everything added so far.
+func (b *ClimatologyBuilder) Build() Climatology {
+	c := Climatology{
+		Version:     climatologyVersion,
+		Station:     b.station,
+		Start:       b.start,
+		End:         b.end,
+		Step:        b.step,
+		GoodQC:      b.opts.GoodQC,
+		LocalTime:   b.opts.LocalTime,
+		Percentiles: b.opts.Percentiles,
+		DayWindow:   b.opts.DayWindow,
+		MinCoverage: b.opts.MinCoverage,
+	}
+	tz := b.h.tz
+	if tz == nil {
+		tz = time.UTC
+	}
+
+	days := make(map[int64]bool)
+	for j, v := range b.h.vars {
+		keys, means := b.h.means(j, b.minSamples())
+
+		var monthly [12][24][]float64
+		var daily [365][24][]float64
+		for i, key := range keys {
+			days[key/24] = true
+			t := keyTime(key, tz)
+			hour := t.Hour()
+			monthly[t.Month()-1][hour] = append(monthly[t.Month()-1][hour], means[i])
+			doy := dayOfYear(t)
+			for off := -b.opts.DayWindow; off <= b.opts.DayWindow; off++ {
+				k := ((doy+off)%365 + 365) % 365
+				daily[k][hour] = append(daily[k][hour], means[i])
+			}
+		}
+
+		vc := VariableClimatology{Name: v.Name, Unit: v.Unit}
+		for m := range monthly {
+			for hour := range monthly[m] {
+				vc.Monthly[m][hour] = band(monthly[m][hour], c.Percentiles)
+			}
+		}
+		for d := range daily {
+			for hour := range daily[d] {
+				vc.DayOfYear[d][hour] = band(daily[d][hour], c.Percentiles)
+			}
+		}
+		c.Variables = append(c.Variables, vc)
+	}
+	c.Days = len(days)
+	return c
+}
+
+// Encoding is how a Climatology is persisted.
+type Encoding int
+
+const (
+	JSON Encoding = iota
+	Gob
+)
+
+// EncodingForPath picks Gob for files ending in .gob and JSON otherwise.
+func EncodingForPath(path string) Encoding {
+	if strings.EqualFold(filepath.Ext(path), ".gob") {
+		return Gob
+	}
+	return JSON
+}
+
+func (c Climatology) Encode(w io.Writer, enc Encoding) error {
+	if enc == Gob {
+		return gob.NewEncoder(w).Encode(c)
+	}
+	return json.NewEncoder(w).Encode(c)
+}
+
+// DecodeClimatology reads a climatology written by Climatology.Encode.
+func DecodeClimatology(r io.Reader, enc Encoding) (Climatology, error) {
+	var (
+		c   Climatology
+		err error
+	)
+	if enc == Gob {
+		err = gob.NewDecoder(r).Decode(&c)
+	} else {
+		err = json.NewDecoder(r).Decode(&c)
+	}
+	if err != nil {
+		return Climatology{}, fmt.Errorf("error decoding climatology: %w", err)
+	}
+	if c.Version != climatologyVersion {
+		return Climatology{}, fmt.Errorf("unsupported climatology version %d, expected %d", c.Version, climatologyVersion)
+	}
+	return c, nil
+}
+
+// Score compares a value with a band. Z is the anomaly in standard deviations, and Outside is -1 below
+// the lowest percentile of the band, 1 above the highest and 0 within it.
+type Score struct {
+	N       int     `json:"n"`
+	Mean    float64 `json:"mean"`
+	Anomaly float64 `json:"anomaly"`
+	Z       float64 `json:"z"`
+	Outside int     `json:"outside"`
+}
+
+func score(value float64, b Band) Score {
+	s := Score{N: b.N, Mean: b.Mean, Anomaly: value - b.Mean}
+	if b.StdDev > 0 {
+		s.Z = s.Anomaly / b.StdDev
+	}
+	if n := len(b.Percentiles); n > 0 {
+		switch {
+		case value < b.Percentiles[0]:
+			s.Outside = -1
+		case value > b.Percentiles[n-1]:
+			s.Outside = 1
+		}
+	}
+	return s
+}
+
+// Anomaly is an hourly mean of a new day scored against the climatology.
+type Anomaly struct {
+	Time      time.Time `json:"time"` // start of the hour
+	Variable  string    `json:"variable"`
+	Value     float64   `json:"value"`
+	Monthly   Score     `json:"monthly"`
+	DayOfYear Score     `json:"day_of_year"`
+}
+
+type Anomalies []Anomaly
+
+// Anomalies scores the hourly means of st, binned as the climatology was, against its bands. Hours without
+// a climatological band with at least two values in either are skipped.
+func (c Climatology) Anomalies(st surfrad.Station) (Anomalies, error) {
+	if c.Station != "" && st.StationName != c.Station {
+		return nil, fmt.Errorf("%w: %q, climatology is for %q", surfrad.ErrStationMismatch, st.StationName, c.Station)
+	}
+
+	vars := make([]surfrad.Variable, len(c.Variables))
+	for i, vc := range c.Variables {
+		v, ok := surfrad.LookupVariable(vc.Name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", surfrad.ErrUnknownVariable, vc.Name)
+		}
+		vars[i] = v
+	}
+
+	h := newHourly(vars, c.GoodQC, c.LocalTime)
+	h.add(st)
+	b := ClimatologyBuilder{opts: ClimatologyOptions{MinCoverage: c.MinCoverage}, step: c.Step}
+
+	var out Anomalies
+	for j, vc := range c.Variables {
+		keys, means := h.means(j, b.minSamples())
+		for i, key := range keys {
+			t := keyTime(key, h.tz)
+			monthly, daily := vc.Monthly[t.Month()-1][t.Hour()], vc.DayOfYear[dayOfYear(t)][t.Hour()]
+			if monthly.N < 2 && daily.N < 2 {
+				continue
+			}
+			out = append(out, Anomaly{
+				Time:      t,
+				Variable:  vc.Name,
+				Value:     means[i],
+				Monthly:   score(means[i], monthly),
+				DayOfYear: score(means[i], daily),
+			})
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool { return out[a].Time.Before(out[b].Time) })
+	return out, nil
+}
+
+// WriteText writes the anomalies as a table, marking values outside the bands with < or >.
+func (a Anomalies) WriteText(w io.Writer) error {
+	mark := func(s Score) string {
+		switch s.Outside {
+		case -1:
+			return "<"
+		case 1:
+			return ">"
+		}
+		return ""
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprint(tw, "hour\tvariable\tvalue\tmonth mean\tanomaly\tz\t\tday mean\tanomaly\tz\t\t\n")
+	for _, x := range a {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%.2f\t%.2f\t%+.2f\t%+.2f\t%s\t%.2f\t%+.2f\t%+.2f\t%s\t\n",
+			x.Time.Format("2006-01-02T15:04Z07:00"), x.Variable, x.Value,
+			x.Monthly.Mean, x.Monthly.Anomaly, x.Monthly.Z, mark(x.Monthly),
+			x.DayOfYear.Mean, x.DayOfYear.Anomaly, x.DayOfYear.Z, mark(x.DayOfYear))
+	}
+	return tw.Flush()
+}
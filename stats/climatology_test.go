@@ -0,0 +1,229 @@
+package stats
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+// shiftedYears returns the station moved back by years, with temp raised by the same number of degrees.
+func shiftedYears(st surfrad.Station, years int) surfrad.Station {
+	temp, _ := surfrad.LookupVariable("temp")
+	out := st
+	out.Entries = make([]surfrad.Data, len(st.Entries))
+	copy(out.Entries, st.Entries)
+	for i := range out.Entries {
+		d := &out.Entries[i]
+		d.Timestamp = d.Timestamp.AddDate(-years, 0, 0)
+		if !temp.Missing(d) {
+			temp.Set(d, temp.Value(d)+float64(years), temp.QC(d))
+		}
+	}
+	return out
+}
+
+func buildClimatology(t *testing.T, opts ClimatologyOptions, stations ...surfrad.Station) Climatology {
+	t.Helper()
+	b, err := NewClimatologyBuilder(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range stations {
+		if err = b.Add(st); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b.Build()
+}
+
+func TestClimatology(t *testing.T) {
+	st := readTestStation(t)
+	opts := DefaultClimatologyOptions()
+	opts.Variables = []string{"temp"}
+	c := buildClimatology(t, opts, st, shiftedYears(st, 1), shiftedYears(st, 2))
+
+	if c.Days != 3 || c.Station != st.StationName || len(c.Variables) != 1 {
+		t.Fatalf("unexpected climatology: %d days, station %q, %d variables", c.Days, c.Station, len(c.Variables))
+	}
+
+	cols := st.Columns()
+	temps, _ := cols.Masked("temp", false)
+	var sum float64
+	var n int
+	for i, ts := range cols.Time {
+		if mid := ts.Add(-30 * time.Second); mid.Hour() == 12 && mid.Day() == 17 && !math.IsNaN(temps[i]) {
+			sum += temps[i]
+			n++
+		}
+	}
+	mean := sum / float64(n)
+
+	tests := []struct {
+		name string
+		band Band
+		n    int
+	}{
+		{"month", c.Variables[0].Monthly[1][12], 3},
+		{"day", c.Variables[0].DayOfYear[47][12], 3},
+		{"window start", c.Variables[0].DayOfYear[40][12], 3},
+		{"window end", c.Variables[0].DayOfYear[54][12], 3},
+		{"outside window", c.Variables[0].DayOfYear[55][12], 0},
+		{"other month", c.Variables[0].Monthly[2][12], 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.band.N != tt.n {
+				t.Fatalf("expected %d hourly means, got %d", tt.n, tt.band.N)
+			}
+			if tt.n == 0 {
+				return
+			}
+			if math.Abs(tt.band.Mean-(mean+1)) > 1e-9 || math.Abs(tt.band.StdDev-1) > 1e-9 {
+				t.Errorf("expected mean %.3f and std dev 1, got %.3f and %.3f", mean+1, tt.band.Mean, tt.band.StdDev)
+			}
+			if len(tt.band.Percentiles) != 4 || math.Abs(tt.band.Percentiles[0]-(mean+0.2)) > 1e-9 {
+				t.Errorf("unexpected percentiles %v", tt.band.Percentiles)
+			}
+		})
+	}
+
+	other := st
+	other.StationName = "Table Mountain"
+	b, _ := NewClimatologyBuilder(opts)
+	_ = b.Add(st)
+	if err := b.Add(other); !errors.Is(err, surfrad.ErrStationMismatch) {
+		t.Errorf("expected ErrStationMismatch, got %v", err)
+	}
+	if _, err := NewClimatologyBuilder(ClimatologyOptions{Variables: []string{"nope"}}); !errors.Is(err, surfrad.ErrUnknownVariable) {
+		t.Errorf("expected ErrUnknownVariable, got %v", err)
+	}
+	for _, bad := range []ClimatologyOptions{{Percentiles: []float64{101}}, {DayWindow: 183}, {MinCoverage: 1.5}} {
+		if _, err := NewClimatologyBuilder(bad); err == nil {
+			t.Errorf("expected an error for %+v", bad)
+		}
+	}
+	if _, err := NewClimatologyBuilder(ClimatologyOptions{DayWindow: 182, MinCoverage: 1}); err != nil {
+		t.Errorf("the widest window and full coverage should be accepted: %v", err)
+	}
+}
+
+func TestClimatologyCalendar(t *testing.T) {
+	st := readTestStation(t)
+	// moved moves the day of the test data, 17 February 2024, to the given date
+	moved := func(year int, month time.Month, day int) surfrad.Station {
+		out := st
+		out.Entries = make([]surfrad.Data, len(st.Entries))
+		copy(out.Entries, st.Entries)
+		days := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Sub(time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC)) / (24 * time.Hour)
+		for i := range out.Entries {
+			out.Entries[i].Timestamp = out.Entries[i].Timestamp.AddDate(0, 0, int(days))
+		}
+		return out
+	}
+
+	opts := ClimatologyOptions{Variables: []string{"temp"}, DayWindow: 1}
+	c := buildClimatology(t, opts, moved(2023, 12, 31), moved(2024, 2, 29), moved(2024, 3, 1), moved(2023, 3, 1))
+	days := c.Variables[0].DayOfYear
+
+	tests := []struct {
+		name string
+		doy  int
+		n    int
+	}{
+		{"new year pools the last day of a common year", 0, 1},
+		{"last day", 364, 1},
+		{"leap day folds onto 28 February", 58, 3},
+		{"1 March lines up across years", 59, 3},
+		{"2 March", 60, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if n := days[tt.doy][12].N; n != tt.n {
+				t.Errorf("expected %d hourly means, got %d", tt.n, n)
+			}
+		})
+	}
+}
+
+func TestClimatologyEncoding(t *testing.T) {
+	st := readTestStation(t)
+	c := buildClimatology(t, ClimatologyOptions{Variables: []string{"temp", "dw_solar"}}, st)
+
+	for _, enc := range []Encoding{JSON, Gob} {
+		var buf bytes.Buffer
+		if err := c.Encode(&buf, enc); err != nil {
+			t.Fatal(err)
+		}
+		got, err := DecodeClimatology(&buf, enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, c) {
+			t.Errorf("encoding %d: climatology changed in the round trip", enc)
+		}
+	}
+
+	var buf bytes.Buffer
+	old := c
+	old.Version = climatologyVersion + 1
+	_ = old.Encode(&buf, JSON)
+	if _, err := DecodeClimatology(&buf, JSON); err == nil {
+		t.Error("expected an error decoding another version")
+	}
+
+	if EncodingForPath("clim.GOB") != Gob || EncodingForPath("clim.json") != JSON {
+		t.Error("unexpected encoding for path")
+	}
+}
+
+func TestAnomalies(t *testing.T) {
+	st := readTestStation(t)
+	opts := DefaultClimatologyOptions()
+	opts.Variables = []string{"temp"}
+	c := buildClimatology(t, opts, st, shiftedYears(st, 1), shiftedYears(st, 2))
+
+	anomalies, err := c.Anomalies(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anomalies) == 0 {
+		t.Fatal("no anomalies")
+	}
+	for _, a := range anomalies {
+		for _, s := range []Score{a.Monthly, a.DayOfYear} {
+			if s.N != 3 || math.Abs(s.Anomaly+1) > 1e-9 || math.Abs(s.Z+1) > 1e-9 || s.Outside != -1 {
+				t.Fatalf("%s: unexpected score %+v", a.Time, s)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err = anomalies.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("-1.00")) {
+		t.Errorf("unexpected text:\n%s", buf.String())
+	}
+
+	local := opts
+	local.LocalTime = true
+	lc := buildClimatology(t, local, st, shiftedYears(st, 1))
+	anomalies, err = lc.Anomalies(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anomalies) == 0 || anomalies[0].Time.Location().String() != st.TimeZone().String() {
+		t.Errorf("expected anomalies in local time")
+	}
+
+	other := st
+	other.StationName = "Table Mountain"
+	if _, err = c.Anomalies(other); !errors.Is(err, surfrad.ErrStationMismatch) {
+		t.Errorf("expected ErrStationMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+	"time"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+var ErrTooFewStations = errors.New("comparison needs at least two stations")
+
+type CompareOptions struct {
+	// Variables to compare by name; empty means every measured variable.
+	Variables []string
+	// GoodQC keeps only values flagged good at both stations.
+	GoodQC bool
+	// MaxLag bounds the cross-correlation lags, which run from -MaxLag to MaxLag in steps of Step.
+	// Zero computes no lagged correlations.
+	MaxLag time.Duration
+	// Step is the lag step; zero uses the sampling interval of the first station.
+	Step time.Duration
+}
+
+// Lag is the correlation of one station with another shifted in time. A positive lag pairs the first
+// station at t with the second at t+Lag, so a peak there means the second station lags behind.
+// Lags where the correlation is undefined are left out.
+type Lag struct {
+	Lag         time.Duration `json:"lag"`
+	N           int           `json:"n"`
+	Correlation float64       `json:"correlation"`
+}
+
+// Pair compares one variable between two stations at their common timestamps. Bias and RMSE are of B
+// relative to A. Statistics that need more values than there are are zero, except correlations, which
+// are nil where undefined, as for a constant series.
+type Pair struct {
+	A        surfrad.StationName `json:"a"`
+	B        surfrad.StationName `json:"b"`
+	Variable string              `json:"variable"`
+	Unit     string              `json:"unit"`
+
+	N           int      `json:"n"`
+	MeanA       float64  `json:"mean_a"`
+	MeanB       float64  `json:"mean_b"`
+	Bias        float64  `json:"bias"`
+	RMSE        float64  `json:"rmse"`
+	Correlation *float64 `json:"correlation"`
+
+	// BestLag is the lag with the highest defined correlation, BestCorrelation, among Lags; without lags
+	// it's zero at Correlation.
+	BestLag         time.Duration `json:"best_lag"`
+	BestCorrelation *float64      `json:"best_correlation"`
+	Lags            []Lag         `json:"lags,omitempty"`
+}
+
+// Comparison compares every pair of stations, in the order given.
+type Comparison struct {
+	Stations []surfrad.StationName `json:"stations"`
+	Start    time.Time             `json:"start"`
+	End      time.Time             `json:"end"`
+	Common   int                   `json:"common"` // timestamps present at every station
+	Pairs    []Pair                `json:"pairs"`
+}
+
+// Correlation returns the Pearson correlation of xs and ys, which must be the same length, or NaN if
+// either is constant or there are fewer than two values.
+func Correlation(xs, ys []float64) float64 {
+	if len(xs) < 2 || len(xs) != len(ys) {
+		return math.NaN()
+	}
+	mx, my := Mean(xs), Mean(ys)
+	var sxy, sxx, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return math.NaN()
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// series is one station's columns with an index by timestamp.
+type series struct {
+	st    surfrad.Station
+	cols  surfrad.Columns
+	index map[int64]int
+}
+
+func newSeries(st surfrad.Station) series {
+	s := series{st: st, cols: st.Columns(), index: make(map[int64]int, st.Len())}
+	for i, t := range s.cols.Time {
+		s.index[t.Unix()] = i
+	}
+	return s
+}
+
+// value returns v at t, and whether it's usable.
+func (s series) value(v surfrad.Variable, t time.Time, goodQC bool) (float64, bool) {
+	i, ok := s.index[t.Unix()]
+	if !ok {
+		return 0, false
+	}
+	values, _ := s.cols.Values(v.Name)
+	qc := surfrad.QCGood
+	if flags, ok := s.cols.Flags(v.Name); ok {
+		qc = flags[i]
+	}
+	if surfrad.IsMissing(values[i], qc) || (goodQC && qc != surfrad.QCGood) {
+		return 0, false
+	}
+	return values[i], true
+}
+
+// pairs returns the usable values of v at a's times and b's times shifted by lag.
+func pairs(a, b series, times []time.Time, v surfrad.Variable, lag time.Duration, goodQC bool) (xs, ys []float64) {
+	for _, t := range times {
+		x, ok := a.value(v, t, goodQC)
+		if !ok {
+			continue
+		}
+		y, ok := b.value(v, t.Add(lag), goodQC)
+		if !ok {
+			continue
+		}
+		xs, ys = append(xs, x), append(ys, y)
+	}
+	return xs, ys
+}
+
+// correlation returns the correlation of xs and ys, or nil if it's undefined.
+func correlation(xs, ys []float64) *float64 {
+	r := Correlation(xs, ys)
+	if math.IsNaN(r) || math.IsInf(r, 0) {
+		return nil
+	}
+	return &r
+}
+
+func comparePair(a, b series, times []time.Time, v surfrad.Variable, opts CompareOptions, step time.Duration) Pair {
+	p := Pair{A: a.st.StationName, B: b.st.StationName, Variable: v.Name, Unit: v.Unit}
+
+	xs, ys := pairs(a, b, times, v, 0, opts.GoodQC)
+	p.N = len(xs)
+	if p.N == 0 {
+		return p
+	}
+
+	var se float64
+	for i := range xs {
+		se += (ys[i] - xs[i]) * (ys[i] - xs[i])
+	}
+	p.MeanA, p.MeanB = Mean(xs), Mean(ys)
+	p.Bias = p.MeanB - p.MeanA
+	p.RMSE = math.Sqrt(se / float64(p.N))
+	p.Correlation = correlation(xs, ys)
+	if opts.MaxLag <= 0 {
+		p.BestCorrelation = p.Correlation
+		return p
+	}
+	// lagged pairs are taken from A's own timestamps, as B's shifted ones needn't be common
+	for lag := -opts.MaxLag; lag <= opts.MaxLag; lag += step {
+		xs, ys := pairs(a, b, a.cols.Time, v, lag, opts.GoodQC)
+		r := correlation(xs, ys)
+		if r == nil {
+			continue
+		}
+		p.Lags = append(p.Lags, Lag{Lag: lag, N: len(xs), Correlation: *r})
+		if best := p.BestCorrelation; best == nil || *r > *best || (*r == *best && lag.Abs() < p.BestLag.Abs()) {
+			p.BestLag, p.BestCorrelation = lag, r
+		}
+	}
+	return p
+}
+
+// Compare aligns the stations on the timestamps common to all of them and compares each variable
+// between every pair.
+func Compare(opts CompareOptions, stations ...surfrad.Station) (Comparison, error) {
+	if len(stations) < 2 {
+		return Comparison{}, ErrTooFewStations
+	}
+	vars := surfrad.MeasuredVariables()
+	if len(opts.Variables) > 0 {
+		var err error
+		if vars, err = surfrad.SelectVariables(opts.Variables); err != nil {
+			return Comparison{}, err
+		}
+	}
+	step := opts.Step
+	if step <= 0 {
+		step = stations[0].SamplingInterval()
+	}
+	if opts.MaxLag > 0 && opts.MaxLag/step > 10000 {
+		return Comparison{}, fmt.Errorf("max lag %s is too long for a %s step", opts.MaxLag, step)
+	}
+
+	all := make([]series, len(stations))
+	for i, st := range stations {
+		all[i] = newSeries(st)
+	}
+
+	var c Comparison
+	for _, st := range stations {
+		c.Stations = append(c.Stations, st.StationName)
+	}
+
+	var common []time.Time
+	for _, t := range all[0].cols.Time {
+		shared := true
+		for _, s := range all[1:] {
+			if _, ok := s.index[t.Unix()]; !ok {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			common = append(common, t)
+		}
+	}
+	c.Common = len(common)
+	if c.Common > 0 {
+		c.Start, c.End = common[0], common[c.Common-1]
+	}
+
+	for i := range all {
+		for j := i + 1; j < len(all); j++ {
+			for _, v := range vars {
+				c.Pairs = append(c.Pairs, comparePair(all[i], all[j], common, v, opts, step))
+			}
+		}
+	}
+	return c, nil
+}
+
+// WriteText writes the comparison as a table, one row per pair of stations and variable.
+func (c Comparison) WriteText(w io.Writer) error {
+	_, _ = fmt.Fprintf(w, "stations: %d, %d common records from %s to %s\n", len(c.Stations), c.Common,
+		c.Start.Format(time.RFC3339), c.End.Format(time.RFC3339))
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprint(tw, "a\tb\tvariable\tunit\tn\tmean a\tmean b\tbias\trmse\tr\tbest lag\tr at lag\t\n")
+	for _, p := range c.Pairs {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d", p.A, p.B, p.Variable, p.Unit, p.N)
+		if p.N == 0 {
+			_, _ = fmt.Fprint(tw, "\t-\t-\t-\t-\t-\t-\t-\t\n")
+			continue
+		}
+		_, _ = fmt.Fprintf(tw, "\t%.2f\t%.2f\t%.2f\t%.2f\t%s", p.MeanA, p.MeanB, p.Bias, p.RMSE, formatCorrelation(p.Correlation))
+		if p.Lags == nil {
+			_, _ = fmt.Fprint(tw, "\t-\t-\t\n")
+			continue
+		}
+		_, _ = fmt.Fprintf(tw, "\t%s\t%s\t\n", p.BestLag, formatCorrelation(p.BestCorrelation))
+	}
+	return tw.Flush()
+}
+
+func formatCorrelation(r *float64) string {
+	if r == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.3f", *r)
+}
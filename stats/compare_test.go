@@ -0,0 +1,180 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+func TestCorrelation(t *testing.T) {
+	cases := []struct {
+		name   string
+		xs, ys []float64
+		expect float64
+	}{
+		{"perfect", []float64{1, 2, 3}, []float64{2, 4, 6}, 1},
+		{"inverse", []float64{1, 2, 3}, []float64{3, 2, 1}, -1},
+		{"partial", []float64{1, 2, 3, 4}, []float64{1, 3, 2, 4}, 0.8},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Correlation(tc.xs, tc.ys); math.Abs(got-tc.expect) > 1e-9 {
+				t.Errorf("got %v, expected %v", got, tc.expect)
+			}
+		})
+	}
+	if !math.IsNaN(Correlation([]float64{1, 1}, []float64{1, 2})) || !math.IsNaN(Correlation([]float64{1}, []float64{1})) {
+		t.Error("expected NaN for constant or short series")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a := readTestStation(t)
+
+	// b reads 2 degrees warmer, sees the sun 5 minutes after a, and misses its first hour
+	b := readTestStation(t)
+	b.StationName = surfrad.StationTableMountain
+	for i := range b.Entries {
+		b.Entries[i].TemperatureC += 2
+		if i >= 5 {
+			b.Entries[i].DownwellingSolar = a.Entries[i-5].DownwellingSolar
+			b.Entries[i].QC.DownwellingSolar = a.Entries[i-5].QC.DownwellingSolar
+		}
+	}
+	b.Entries = b.Entries[60:]
+
+	c, err := Compare(CompareOptions{Variables: []string{"temp", "dw_solar"}, MaxLag: 10 * time.Minute}, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Common != 1380 || !c.Start.Equal(a.Entries[60].Timestamp) || len(c.Pairs) != 2 {
+		t.Fatalf("unexpected comparison of %d common records from %v, %d pairs", c.Common, c.Start, len(c.Pairs))
+	}
+
+	temp := c.Pairs[0]
+	if temp.A != surfrad.StationDesertRock || temp.B != surfrad.StationTableMountain || temp.N != 1380 {
+		t.Errorf("unexpected pair %+v", temp)
+	}
+	if math.Abs(temp.Bias-2) > 1e-9 || math.Abs(temp.RMSE-2) > 1e-9 || temp.Correlation == nil || math.Abs(*temp.Correlation-1) > 1e-9 {
+		t.Errorf("expected a constant 2 degree offset, got bias %v rmse %v r %v", temp.Bias, temp.RMSE, temp.Correlation)
+	}
+	if temp.BestLag != 0 || len(temp.Lags) != 21 || temp.Lags[0].Lag != -10*time.Minute {
+		t.Errorf("unexpected lags, best %v of %d", temp.BestLag, len(temp.Lags))
+	}
+
+	dw := c.Pairs[1]
+	if dw.Correlation == nil || dw.BestCorrelation == nil {
+		t.Fatalf("expected defined dw_solar correlations, got %+v", dw)
+	}
+	if dw.BestLag != 5*time.Minute || math.Abs(*dw.BestCorrelation-1) > 1e-9 || *dw.Correlation >= *dw.BestCorrelation {
+		t.Errorf("expected b to lag by 5 minutes, got %v at r %v (r %v unlagged)", dw.BestLag, *dw.BestCorrelation, *dw.Correlation)
+	}
+
+	var buf bytes.Buffer
+	if err = c.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"1380 common records", "Table Mountain", "dw_solar", "5m0s", "2.00"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("text missing %q:\n%s", want, buf.String())
+		}
+	}
+	if _, err = json.Marshal(c); err != nil {
+		t.Error(err)
+	}
+
+	c, err = Compare(CompareOptions{}, a, b, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Pairs) != 3*(len(surfrad.Variables)-1) || c.Pairs[0].Lags != nil {
+		t.Errorf("expected every pair of three stations without lags, got %d pairs", len(c.Pairs))
+	}
+
+	if _, err = Compare(CompareOptions{}, a); !errors.Is(err, ErrTooFewStations) {
+		t.Errorf("expected ErrTooFewStations, got %v", err)
+	}
+	if _, err = Compare(CompareOptions{Variables: []string{"nope"}}, a, b); err == nil {
+		t.Error("expected an error for an unknown variable")
+	}
+}
+
+func TestCompareUndefined(t *testing.T) {
+	base := time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC)
+	a := surfrad.Station{StationName: surfrad.StationDesertRock}
+	b := surfrad.Station{StationName: surfrad.StationTableMountain}
+	for i, temp := range []float64{1, 2, 3} {
+		ts := base.Add(time.Duration(i) * time.Minute)
+		a.Entries = append(a.Entries, surfrad.Data{Timestamp: ts, TemperatureC: temp, RelativeHumidity: 20})
+		b.Entries = append(b.Entries, surfrad.Data{Timestamp: ts, TemperatureC: 4 - temp, RelativeHumidity: 20})
+	}
+
+	// lags of two minutes pair a single record, whose undefined correlation mustn't beat -1
+	c, err := Compare(CompareOptions{Variables: []string{"temp", "rh"}, MaxLag: 2 * time.Minute}, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	temp, rh := c.Pairs[0], c.Pairs[1]
+	if len(temp.Lags) != 3 || temp.BestLag != 0 || temp.BestCorrelation == nil || math.Abs(*temp.BestCorrelation+1) > 1e-9 {
+		t.Errorf("expected the best of the defined lags, got %v at r %v of %+v", temp.BestLag, temp.BestCorrelation, temp.Lags)
+	}
+	if rh.Correlation != nil || rh.BestCorrelation != nil || rh.Lags != nil {
+		t.Errorf("expected no correlation of constant humidity, got %+v", rh)
+	}
+
+	var buf bytes.Buffer
+	if err = c.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "-1.000") {
+		t.Errorf("text missing the best correlation:\n%s", buf.String())
+	}
+	if out, err := json.Marshal(rh); err != nil || !strings.Contains(string(out), `"correlation":null`) {
+		t.Errorf("expected a null correlation, got %s, %v", out, err)
+	}
+}
+
+func TestCompareLagsOwnSample(t *testing.T) {
+	a := readTestStation(t)
+
+	// b matches a exactly for the two hours c covers and is noisy after, so the common records correlate
+	// better than any lag over a's whole day
+	b := readTestStation(t)
+	b.StationName = surfrad.StationTableMountain
+	for i := 120; i < len(b.Entries); i++ {
+		b.Entries[i].TemperatureC += float64(i%7) - 3
+	}
+	c := readTestStation(t)
+	c.StationName = surfrad.StationBondville
+	c.Entries = c.Entries[:120]
+
+	cmp, err := Compare(CompareOptions{Variables: []string{"temp"}, MaxLag: 3 * time.Minute}, a, b, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmp.Common != 120 {
+		t.Fatalf("expected c to limit the common records to 120, got %d", cmp.Common)
+	}
+
+	p := cmp.Pairs[0]
+	if p.Correlation == nil || math.Abs(*p.Correlation-1) > 1e-9 {
+		t.Fatalf("expected a and b to match on the common records, got r %v", p.Correlation)
+	}
+	if p.BestCorrelation == nil {
+		t.Fatal("expected a best lag")
+	}
+	for _, l := range p.Lags {
+		if l.Correlation > *p.BestCorrelation {
+			t.Errorf("lag %s has r %v above the best, %v at %s", l.Lag, l.Correlation, *p.BestCorrelation, p.BestLag)
+		}
+		if l.Lag == p.BestLag && l.Correlation != *p.BestCorrelation {
+			t.Errorf("best r %v at %s differs from the table's %v", *p.BestCorrelation, p.BestLag, l.Correlation)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Package stats computes descriptive statistics of the variables in SURFRAD station data.
+package stats
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+type Options struct {
+	// Variables to summarize by name; empty means every measured variable.
+	Variables []string
+	// Daytime keeps only records with the sun above MaxZenith, taking the file's solar zenith angle or,
+	// where it's missing, computing it from the station's location.
+	Daytime   bool
+	MaxZenith float64
+	// GoodQC keeps only values flagged good, dropping questionable ones and bad ones that aren't missing.
+	GoodQC bool
+	// Percentiles to report, between 0 and 100.
+	Percentiles []float64
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MaxZenith:   90,
+		Percentiles: []float64{5, 25, 75, 95},
+	}
+}
+
+func (o Options) withDefaults() Options {
+	def := DefaultOptions()
+	if o.MaxZenith <= 0 {
+		o.MaxZenith = def.MaxZenith
+	}
+	if o.Percentiles == nil {
+		o.Percentiles = def.Percentiles
+	}
+	return o
+}
+
+// Extreme is a minimum or maximum and when it happened, the first time if it happened more than once.
+type Extreme struct {
+	Value float64   `json:"value"`
+	Time  time.Time `json:"time"`
+}
+
+type Percentile struct {
+	P     float64 `json:"p"`
+	Value float64 `json:"value"`
+}
+
+// Variable summarizes one variable. When Count is zero the statistics are all zero.
+type Variable struct {
+	Name string `json:"name"`
+	Unit string `json:"unit"`
+
+	Count    int `json:"count"`    // values summarized
+	Missing  int `json:"missing"`  // records where the value is missing
+	Excluded int `json:"excluded"` // values dropped by the QC option
+
+	Min         Extreme      `json:"min"`
+	Max         Extreme      `json:"max"`
+	Mean        float64      `json:"mean"`
+	Median      float64      `json:"median"`
+	StdDev      float64      `json:"std_dev"` // sample standard deviation
+	Percentiles []Percentile `json:"percentiles"`
+}
+
+// Summary holds the statistics of a station's variables.
+type Summary struct {
+	Station surfrad.StationName `json:"station"`
+	Start   time.Time           `json:"start"`
+	End     time.Time           `json:"end"`
+	Records int                 `json:"records"` // records considered, after the daytime option
+	Daytime bool                `json:"daytime"`
+	GoodQC  bool                `json:"good_qc"`
+	// Percentiles are the ones reported for every variable with values.
+	Percentiles []float64 `json:"percentiles"`
+
+	Variables []Variable `json:"variables"`
+}
+
+// Mean returns the mean of xs, or NaN if it's empty.
+func Mean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return math.NaN()
+	}
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// StdDev returns the sample standard deviation of xs, or NaN with fewer than two values.
+func StdDev(xs []float64) float64 {
+	if len(xs) < 2 {
+		return math.NaN()
+	}
+	mean := Mean(xs)
+	var ss float64
+	for _, x := range xs {
+		ss += (x - mean) * (x - mean)
+	}
+	return math.Sqrt(ss / float64(len(xs)-1))
+}
+
+// Quantile returns the p-th percentile, 0 to 100, of sorted values by linear interpolation between
+// closest ranks, or NaN if there are none.
+func Quantile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	if lo >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	if lo < 0 {
+		return sorted[0]
+	}
+	return sorted[lo] + (rank-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
+
+func summarize(v surfrad.Variable, times []time.Time, values []float64, flags []int, keep []bool, opts Options) Variable {
+	out := Variable{Name: v.Name, Unit: v.Unit}
+
+	xs := make([]float64, 0, len(values))
+	for i, value := range values {
+		if !keep[i] {
+			continue
+		}
+		qc := surfrad.QCGood
+		if flags != nil {
+			qc = flags[i]
+		}
+		if surfrad.IsMissing(value, qc) {
+			out.Missing++
+			continue
+		}
+		if opts.GoodQC && qc != surfrad.QCGood {
+			out.Excluded++
+			continue
+		}
+
+		if len(xs) == 0 || value < out.Min.Value {
+			out.Min = Extreme{value, times[i]}
+		}
+		if len(xs) == 0 || value > out.Max.Value {
+			out.Max = Extreme{value, times[i]}
+		}
+		xs = append(xs, value)
+	}
+
+	out.Count = len(xs)
+	if out.Count == 0 {
+		return out
+	}
+
+	out.Mean = Mean(xs)
+	if out.Count > 1 {
+		out.StdDev = StdDev(xs)
+	}
+	sort.Float64s(xs)
+	out.Median = Quantile(xs, 50)
+	for _, p := range opts.Percentiles {
+		out.Percentiles = append(out.Percentiles, Percentile{p, Quantile(xs, p)})
+	}
+	return out
+}
+
+// Summarize computes the statistics of each variable, ignoring missing values.
+func Summarize(st surfrad.Station, opts Options) (Summary, error) {
+	opts = opts.withDefaults()
+
+	vars := surfrad.MeasuredVariables()
+	if len(opts.Variables) > 0 {
+		var err error
+		if vars, err = surfrad.SelectVariables(opts.Variables); err != nil {
+			return Summary{}, err
+		}
+	}
+	for _, p := range opts.Percentiles {
+		if p < 0 || p > 100 {
+			return Summary{}, fmt.Errorf("percentile %v out of range", p)
+		}
+	}
+
+	cols := st.Columns()
+	keep := make([]bool, cols.Len())
+	sum := Summary{Station: st.StationName, Daytime: opts.Daytime, GoodQC: opts.GoodQC, Percentiles: opts.Percentiles}
+	for i := range keep {
+		// a missing zenith is zeroed on parsing, so it's computed rather than read as the sun overhead
+		keep[i] = !opts.Daytime || st.Entries[i].Zenith(st.LocatedAt) < opts.MaxZenith
+		if !keep[i] {
+			continue
+		}
+		if sum.Records == 0 {
+			sum.Start = cols.Time[i]
+		}
+		sum.End = cols.Time[i]
+		sum.Records++
+	}
+
+	for _, v := range vars {
+		values, _ := cols.Values(v.Name)
+		flags, _ := cols.Flags(v.Name)
+		sum.Variables = append(sum.Variables, summarize(v, cols.Time, values, flags, keep, opts))
+	}
+	return sum, nil
+}
+
+// WriteText writes the summary as a table, one row per variable.
+func (s Summary) WriteText(w io.Writer) error {
+	_, _ = fmt.Fprintf(w, "station: %s\n", s.Station)
+	_, _ = fmt.Fprintf(w, "span: %s to %s, %d records", s.Start.Format(time.RFC3339), s.End.Format(time.RFC3339), s.Records)
+	if s.Daytime {
+		_, _ = fmt.Fprint(w, ", daytime only")
+	}
+	if s.GoodQC {
+		_, _ = fmt.Fprint(w, ", good QC only")
+	}
+	_, _ = fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprint(tw, "variable\tunit\tn\tmin\tmean\tmedian\tmax\tstd")
+	for _, p := range s.Percentiles {
+		_, _ = fmt.Fprintf(tw, "\tp%g", p)
+	}
+	_, _ = fmt.Fprint(tw, "\tmin at\tmax at\t\n")
+
+	for _, v := range s.Variables {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d", v.Name, v.Unit, v.Count)
+		if v.Count == 0 {
+			for i := 0; i < 5+len(s.Percentiles)+2; i++ {
+				_, _ = fmt.Fprint(tw, "\t-")
+			}
+			_, _ = fmt.Fprint(tw, "\t\n")
+			continue
+		}
+		_, _ = fmt.Fprintf(tw, "\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f", v.Min.Value, v.Mean, v.Median, v.Max.Value, v.StdDev)
+		for _, p := range v.Percentiles {
+			_, _ = fmt.Fprintf(tw, "\t%.2f", p.Value)
+		}
+		_, _ = fmt.Fprintf(tw, "\t%s\t%s\t\n", v.Min.Time.Format(time.RFC3339), v.Max.Time.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
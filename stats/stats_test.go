@@ -0,0 +1,162 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+func readTestStation(t *testing.T) surfrad.Station {
+	t.Helper()
+	f, err := os.Open("../testdata/dra24048.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	station, err := surfrad.ReadData(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return station
+}
+
+func TestHelpers(t *testing.T) {
+	xs := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	if got := Mean(xs); got != 5 {
+		t.Errorf("Mean == %v", got)
+	}
+	if got := StdDev(xs); math.Abs(got-2.13809) > 1e-5 {
+		t.Errorf("StdDev == %v", got)
+	}
+
+	cases := []struct{ p, expect float64 }{
+		{0, 2}, {100, 9}, {50, 4.5}, {25, 4}, {90, 7.6},
+	}
+	for _, tc := range cases {
+		if got := Quantile(xs, tc.p); math.Abs(got-tc.expect) > 1e-9 {
+			t.Errorf("Quantile(%v) == %v, expected %v", tc.p, got, tc.expect)
+		}
+	}
+
+	if !math.IsNaN(Mean(nil)) || !math.IsNaN(StdDev([]float64{1})) || !math.IsNaN(Quantile(nil, 50)) {
+		t.Error("expected NaN without enough values")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	st := readTestStation(t)
+	st.Entries[100].TemperatureC, st.Entries[100].QC.TemperatureC = 0, surfrad.QCMissing
+	st.Entries[101].QC.TemperatureC = surfrad.QCQuestionable
+
+	sum, err := Summarize(st, Options{Variables: []string{"temp", "dw_solar"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Records != 1440 || len(sum.Variables) != 2 || !sum.Start.Equal(st.Entries[0].Timestamp) {
+		t.Fatalf("unexpected summary %+v", sum)
+	}
+
+	temp := sum.Variables[0]
+	if temp.Name != "temp" || temp.Unit != "degC" || temp.Count != 1439 || temp.Missing != 1 || temp.Excluded != 0 {
+		t.Errorf("unexpected counts %+v", temp)
+	}
+
+	var xs []float64
+	min, max := math.Inf(1), math.Inf(-1)
+	var minAt, maxAt time.Time
+	for i, d := range st.Entries {
+		if i == 100 {
+			continue
+		}
+		xs = append(xs, d.TemperatureC)
+		if d.TemperatureC < min {
+			min, minAt = d.TemperatureC, d.Timestamp
+		}
+		if d.TemperatureC > max {
+			max, maxAt = d.TemperatureC, d.Timestamp
+		}
+	}
+	sort.Float64s(xs)
+	if temp.Min != (Extreme{min, minAt}) || temp.Max != (Extreme{max, maxAt}) {
+		t.Errorf("extremes %+v %+v, expected %v at %v and %v at %v", temp.Min, temp.Max, min, minAt, max, maxAt)
+	}
+	if math.Abs(temp.Mean-Mean(xs)) > 1e-9 || temp.Median != Quantile(xs, 50) || math.Abs(temp.StdDev-StdDev(xs)) > 1e-9 {
+		t.Errorf("unexpected moments %+v", temp)
+	}
+	if len(temp.Percentiles) != 4 || temp.Percentiles[3] != (Percentile{95, Quantile(xs, 95)}) {
+		t.Errorf("unexpected percentiles %+v", temp.Percentiles)
+	}
+
+	good, err := Summarize(st, Options{Variables: []string{"temp"}, GoodQC: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := good.Variables[0]; v.Count != 1438 || v.Excluded != 1 {
+		t.Errorf("expected the questionable value to be excluded, got %+v", v)
+	}
+
+	day, err := Summarize(st, Options{Daytime: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if day.Records == 0 || day.Records >= 1440 || len(day.Variables) != len(surfrad.Variables)-1 {
+		t.Fatalf("unexpected daytime summary of %d records, %d variables", day.Records, len(day.Variables))
+	}
+	for _, d := range st.Entries {
+		if d.Timestamp.Equal(day.Start) && d.SolarZenithAngle >= 90 {
+			t.Error("daytime summary starts at night")
+		}
+	}
+	if dw := day.Variables[0]; dw.Count+dw.Missing+dw.Excluded != day.Records || dw.Min.Value < -10 {
+		t.Errorf("unexpected daytime dw_solar %+v", dw)
+	}
+
+	// a missing zenith at local midnight is computed, not taken for the sun overhead
+	night := st
+	night.Entries = append([]surfrad.Data(nil), st.Entries...)
+	night.Entries[480].SolarZenithAngle = 0
+	if again, _ := Summarize(night, Options{Daytime: true}); again.Records != day.Records {
+		t.Errorf("missing zenith changed the daytime records from %d to %d", day.Records, again.Records)
+	}
+
+	if _, err = Summarize(st, Options{Variables: []string{"nope"}}); err == nil {
+		t.Error("expected an error for an unknown variable")
+	}
+	if _, err = Summarize(st, Options{Percentiles: []float64{150}}); err == nil {
+		t.Error("expected an error for a percentile above 100")
+	}
+}
+
+func TestSummaryOutput(t *testing.T) {
+	st := readTestStation(t)
+	st.Entries = st.Entries[:0:0]
+
+	sum, err := Summarize(readTestStation(t), Options{Variables: []string{"temp"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty, err := Summarize(st, Options{Variables: []string{"temp"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []Summary{sum, empty} {
+		var buf bytes.Buffer
+		if err = s.WriteText(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "p95") || !strings.Contains(buf.String(), "temp") {
+			t.Errorf("unexpected text:\n%s", buf.String())
+		}
+		if _, err = json.Marshal(s); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+// Store is a local SQLite cache of parsed SURFRAD data.
+type Store struct {
+	db *sql.DB
+}
+
+var (
+	ErrUnknownStation = errors.New("unknown station")
+	ErrSchemaVersion  = errors.New("unsupported schema version")
+)
+
+// schemaVersion is stored as the database's user_version and changes whenever the columns do.
+const schemaVersion = 1
+
+const schema = `
+CREATE TABLE IF NOT EXISTS stations (
+	id        TEXT PRIMARY KEY,
+	name      TEXT NOT NULL,
+	latitude  REAL NOT NULL,
+	longitude REAL NOT NULL,
+	elevation INTEGER NOT NULL,
+	version   INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS measurements (
+	station   TEXT NOT NULL REFERENCES stations(id),
+	timestamp INTEGER NOT NULL,
+	%s,
+	PRIMARY KEY (station, timestamp)
+) WITHOUT ROWID;
+`
+
+// columns are the measurement columns: the file's time columns, then each variable's value and flag as
+// described by surfrad.Variables.
+var columns = func() []column {
+	cols := []column{
+		intColumn("year", func(d *surfrad.Data) *int { return &d.RawTimestamp.Year }),
+		intColumn("jday", func(d *surfrad.Data) *int { return &d.RawTimestamp.JDay }),
+		intColumn("month", func(d *surfrad.Data) *int { return &d.RawTimestamp.Month }),
+		intColumn("day", func(d *surfrad.Data) *int { return &d.RawTimestamp.Day }),
+		intColumn("hour", func(d *surfrad.Data) *int { return &d.RawTimestamp.Hour }),
+		intColumn("minute", func(d *surfrad.Data) *int { return &d.RawTimestamp.Minute }),
+		{"decimal_time", "REAL",
+			func(d *surfrad.Data) any { return d.RawTimestamp.Decimal },
+			func(d *surfrad.Data, x float64) { d.RawTimestamp.Decimal = x }},
+	}
+	for _, v := range surfrad.Variables {
+		v := v
+		cols = append(cols, column{v.JSONName, "REAL",
+			func(d *surfrad.Data) any { return v.Value(d) },
+			func(d *surfrad.Data, x float64) { v.Set(d, x, flag(v, d)) }})
+	}
+	for _, v := range surfrad.Variables {
+		if v.QC == nil {
+			continue
+		}
+		v := v
+		cols = append(cols, column{"qc_" + v.Name, "INTEGER",
+			func(d *surfrad.Data) any { return v.QC(d) },
+			func(d *surfrad.Data, x float64) { v.Set(d, v.Value(d), int(x)) }})
+	}
+	return cols
+}()
+
+// column is a measurement column and how it's read from and written to a record.
+type column struct {
+	name string
+	kind string
+	get  func(d *surfrad.Data) any
+	set  func(d *surfrad.Data, x float64)
+}
+
+func intColumn(name string, field func(d *surfrad.Data) *int) column {
+	return column{name, "INTEGER",
+		func(d *surfrad.Data) any { return *field(d) },
+		func(d *surfrad.Data, x float64) { *field(d) = int(x) }}
+}
+
+func flag(v surfrad.Variable, d *surfrad.Data) int {
+	if v.QC == nil {
+		return surfrad.QCGood
+	}
+	return v.QC(d)
+}
+
+func fieldValues(d *surfrad.Data) []any {
+	values := make([]any, len(columns))
+	for i, c := range columns {
+		values[i] = c.get(d)
+	}
+	return values
+}
+
+// Open opens (creating if needed) the SQLite database at path and ensures the schema exists. A database
+// written with another schema version fails with ErrSchemaVersion, and has to be rebuilt.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+	// sqlite only allows a single writer; serializing here avoids SQLITE_BUSY under concurrent ingest.
+	db.SetMaxOpenConns(1)
+
+	defs := make([]string, len(columns))
+	for i, c := range columns {
+		defs[i] = c.name + " " + c.kind + " NOT NULL"
+	}
+
+	var version int
+	if err = db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("error reading schema version: %w", err)
+	}
+	if version == 0 {
+		// an unversioned database with tables predates versioning and can't be written to
+		var tables int
+		if err = db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table'").Scan(&tables); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("error reading schema: %w", err)
+		}
+		if tables > 0 {
+			_ = db.Close()
+			return nil, fmt.Errorf("%w: %s has no version, expected %d", ErrSchemaVersion, path, schemaVersion)
+		}
+	} else if version != schemaVersion {
+		_ = db.Close()
+		return nil, fmt.Errorf("%w %d in %s, expected %d", ErrSchemaVersion, version, path, schemaVersion)
+	}
+
+	if _, err = db.Exec(fmt.Sprintf(schema, strings.Join(defs, ",\n\t"))); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("error creating schema: %w", err)
+	}
+	if _, err = db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion)); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("error writing schema version: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func insertStatement() string {
+	names := make([]string, len(columns))
+	marks := make([]string, len(columns))
+	updates := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+		marks[i] = "?"
+		updates[i] = c.name + " = excluded." + c.name
+	}
+	return "INSERT INTO measurements (station, timestamp, " + strings.Join(names, ", ") + ") " +
+		"VALUES (?, ?, " + strings.Join(marks, ", ") + ") " +
+		"ON CONFLICT (station, timestamp) DO UPDATE SET " + strings.Join(updates, ", ")
+}
+
+// Ingest upserts the station header and all of its entries. Ingesting the same data twice is a no-op,
+// and newer values for an existing station+timestamp replace the old ones.
+func (s *Store) Ingest(ctx context.Context, station surfrad.Station) error {
+	sid, ok := surfrad.GetStationID(station.StationName)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownStation, station.StationName)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.ExecContext(ctx,
+		`INSERT INTO stations (id, name, latitude, longitude, elevation, version) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, latitude = excluded.latitude,
+		longitude = excluded.longitude, elevation = excluded.elevation, version = excluded.version`,
+		sid.String(), station.StationName.String(), station.LocatedAt.Latitude,
+		station.LocatedAt.Longitude, station.LocatedAt.Elevation, station.Version,
+	); err != nil {
+		return fmt.Errorf("error upserting station %s: %w", sid, err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, insertStatement())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i := range station.Entries {
+		entry := &station.Entries[i]
+		args := append([]any{sid.String(), entry.Timestamp.Unix()}, fieldValues(entry)...)
+		if _, err = stmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("error upserting entry %s: %w", entry.Timestamp, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Stations returns the identifiers of every station present in the store.
+func (s *Store) Stations(ctx context.Context) ([]surfrad.StationID, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM stations ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []surfrad.StationID
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		var sid surfrad.StationID
+		copy(sid[:], []rune(id))
+		ids = append(ids, sid)
+	}
+	return ids, rows.Err()
+}
+
+// Query returns the station header and every entry in [start, end), ordered by timestamp.
+// A zero start or end leaves that side of the range open.
+func (s *Store) Query(ctx context.Context, sid surfrad.StationID, start, end time.Time) (surfrad.Station, error) {
+	var station surfrad.Station
+
+	var name string
+	err := s.db.QueryRowContext(ctx,
+		"SELECT name, latitude, longitude, elevation, version FROM stations WHERE id = ?", sid.String(),
+	).Scan(&name, &station.LocatedAt.Latitude, &station.LocatedAt.Longitude, &station.LocatedAt.Elevation, &station.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return station, fmt.Errorf("%w: %s", ErrUnknownStation, sid)
+	}
+	if err != nil {
+		return station, err
+	}
+	station.StationName = surfrad.StationName(name)
+
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+
+	query := "SELECT timestamp, " + strings.Join(names, ", ") + " FROM measurements WHERE station = ?"
+	args := []any{sid.String()}
+	if !start.IsZero() {
+		query += " AND timestamp >= ?"
+		args = append(args, start.Unix())
+	}
+	if !end.IsZero() {
+		query += " AND timestamp < ?"
+		args = append(args, end.Unix())
+	}
+	query += " ORDER BY timestamp"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return station, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			entry surfrad.Data
+			ts    int64
+		)
+		values := make([]float64, len(columns))
+		dest := make([]any, len(columns)+1)
+		dest[0] = &ts
+		for i := range values {
+			dest[i+1] = &values[i]
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return station, err
+		}
+		for i, c := range columns {
+			c.set(&entry, values[i])
+		}
+		entry.Timestamp = time.Unix(ts, 0).UTC()
+		station.Entries = append(station.Entries, entry)
+	}
+
+	return station, rows.Err()
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"git.tcp.direct/kayos/surfrad"
+)
+
+func readTestStation(t *testing.T) surfrad.Station {
+	t.Helper()
+	f, err := os.Open("../testdata/dra24048.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	station, err := surfrad.ReadData(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return station
+}
+
+func TestIngestAndQuery(t *testing.T) {
+	ctx := context.Background()
+	station := readTestStation(t)
+
+	store, err := Open(filepath.Join(t.TempDir(), "surfrad.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// ingesting twice must not duplicate anything
+	for i := 0; i < 2; i++ {
+		if err = store.Ingest(ctx, station); err != nil {
+			t.Fatalf("Ingest() pass %d: %v", i, err)
+		}
+	}
+
+	ids, err := store.Stations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != surfrad.StationIDDesertRock {
+		t.Fatalf("Stations() == %v, expected [%s]", ids, surfrad.StationIDDesertRock)
+	}
+
+	got, err := store.Query(ctx, surfrad.StationIDDesertRock, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, station) {
+		t.Errorf("Query() did not round trip: got %d entries, expected %d", got.Len(), station.Len())
+	}
+
+	cases := []struct {
+		name     string
+		start    time.Time
+		end      time.Time
+		expected int
+	}{
+		{"first hour", time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 17, 1, 0, 0, 0, time.UTC), 60},
+		{"open start", time.Time{}, time.Date(2024, 2, 17, 0, 10, 0, 0, time.UTC), 10},
+		{"outside", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := store.Query(ctx, surfrad.StationIDDesertRock, tc.start, tc.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Len() != tc.expected {
+				t.Errorf("Query() returned %d entries, expected %d", res.Len(), tc.expected)
+			}
+		})
+	}
+}
+
+func TestIngestUpdates(t *testing.T) {
+	ctx := context.Background()
+	station := readTestStation(t)
+	station.Entries = station.Entries[:5]
+
+	store, err := Open(filepath.Join(t.TempDir(), "surfrad.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if err = store.Ingest(ctx, station); err != nil {
+		t.Fatal(err)
+	}
+
+	station.Entries[0].TemperatureC = 42.5
+	if err = store.Ingest(ctx, station); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Query(ctx, surfrad.StationIDDesertRock, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Len() != 5 {
+		t.Fatalf("Query() returned %d entries, expected 5", got.Len())
+	}
+	if got.Entries[0].TemperatureC != 42.5 {
+		t.Errorf("upsert did not replace value: got %v", got.Entries[0].TemperatureC)
+	}
+}
+
+func TestQueryUnknownStation(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "surfrad.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if _, err = store.Query(context.Background(), surfrad.StationIDBondville, time.Time{}, time.Time{}); err == nil {
+		t.Error("Query() for an empty store should fail")
+	}
+	if err = store.Ingest(context.Background(), surfrad.Station{StationName: "Nowhere, Narnia"}); err == nil {
+		t.Error("Ingest() for an unknown station should fail")
+	}
+}
+
+func TestColumns(t *testing.T) {
+	ctx := context.Background()
+	station := readTestStation(t)
+	station.Entries = station.Entries[:1]
+	d := &station.Entries[0]
+	for i, v := range surfrad.Variables {
+		v.Set(d, float64(i)+0.5, i+10)
+	}
+
+	store, err := Open(filepath.Join(t.TempDir(), "surfrad.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err = store.Ingest(ctx, station); err != nil {
+		t.Fatal(err)
+	}
+
+	// every variable lands in its own value and flag columns
+	for i, v := range surfrad.Variables {
+		var value float64
+		if err = store.db.QueryRow("SELECT " + v.JSONName + " FROM measurements").Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		if value != float64(i)+0.5 {
+			t.Errorf("%s column holds %v, expected %v", v.JSONName, value, float64(i)+0.5)
+		}
+		if v.QC == nil {
+			continue
+		}
+		var qc int
+		if err = store.db.QueryRow("SELECT qc_" + v.Name + " FROM measurements").Scan(&qc); err != nil {
+			t.Fatal(err)
+		}
+		if qc != i+10 {
+			t.Errorf("qc_%s column holds %d, expected %d", v.Name, qc, i+10)
+		}
+	}
+
+	got, err := store.Query(ctx, surfrad.StationIDDesertRock, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Entries, station.Entries) {
+		t.Errorf("Query() == %+v, expected %+v", got.Entries, station.Entries)
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	dir := t.TempDir()
+
+	// a database from before the flag columns, without a version
+	old := filepath.Join(dir, "old.db")
+	db, err := sql.Open("sqlite", old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Exec("CREATE TABLE measurements (station TEXT, timestamp INTEGER, temperature REAL)"); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+	if _, err = Open(old); !errors.Is(err, ErrSchemaVersion) {
+		t.Errorf("expected ErrSchemaVersion for an unversioned database, got %v", err)
+	}
+
+	path := filepath.Join(dir, "surfrad.db")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+	if store, err = Open(path); err != nil {
+		t.Fatalf("reopening the current version: %v", err)
+	}
+	if _, err = store.db.Exec("PRAGMA user_version = 99"); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+	if _, err = Open(path); !errors.Is(err, ErrSchemaVersion) {
+		t.Errorf("expected ErrSchemaVersion for another version, got %v", err)
+	}
+}
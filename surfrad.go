@@ -71,6 +71,8 @@ rh			real	relative humidity (%)
 windspd		real	wind speed (ms^-1)
 winddir		real	wind direction (degrees, clockwise from north)
 pressure		real	station pressure (mb)
+
+Variables describes the measured ones at runtime, with their units and plausible ranges.
 */
 
 type Data struct {
@@ -101,6 +103,54 @@ type Data struct {
 	WindDirectionDegrees     float64 `json:"wind_direction,omitempty"`      // degrees, clockwise from north
 	BarometricPressure       float64 `json:"barometric_pressure,omitempty"` // mb
 
+	QC QCFlags `json:"qc"`
+}
+
+/*
+A QC flag of zero indicates that the corresponding data point is good, having passed all QC checks.
+A value of 1 means the recorded value is beyond a physically possible range, or has been adversely
+affected to produce a knowingly bad value. A value of 2 means the value may be physically possible
+but should be used with scrutiny. Missing values are indicated by -9999.9 and always have a QC flag of 1.
+ParseLine zeroes missing values and flags them QCMissing instead, so they stay distinct from bad ones.
+*/
+
+const (
+	QCGood         = 0
+	QCBad          = 1
+	QCQuestionable = 2
+	QCMissing      = -1
+
+	MissingValue = -9999.9
+)
+
+// QCFlags holds the quality control flag that accompanies each measured value in a record.
+// The solar zenith angle is computed, not measured, and so has no flag.
+type QCFlags struct {
+	DownwellingSolar                  int `json:"dw_solar"`
+	UpwellingSolar                    int `json:"uw_solar"`
+	DirectNormalSolar                 int `json:"direct_n"`
+	DownwellingDiffuseSolar           int `json:"diffuse"`
+	DownwellingIR                     int `json:"dw_ir"`
+	DownwellingIRCaseTemp             int `json:"dw_casetemp"`
+	DownwellingIRDomeTemp             int `json:"dw_dometemp"`
+	UpwellingIR                       int `json:"uw_ir"`
+	UpwellingIRCaseTemp               int `json:"uw_casetemp"`
+	UpwellingIRDomeTemp               int `json:"uw_dometemp"`
+	GlobalUVB                         int `json:"uvb"`
+	PhotosyntheticallyActiveRadiation int `json:"par"`
+	NetSolar                          int `json:"netsolar"`
+	NetIR                             int `json:"netir"`
+	TotalNetRadiation                 int `json:"totalnet"`
+	TemperatureC                      int `json:"temp"`
+	RelativeHumidity                  int `json:"rh"`
+	WindSpeedMetersPerSecond          int `json:"windspd"`
+	WindDirectionDegrees              int `json:"winddir"`
+	BarometricPressure                int `json:"pressure"`
+}
+
+// IsMissing reports whether a value is missing, either still the file's sentinel or flagged QCMissing.
+func IsMissing(value float64, qc int) bool {
+	return value == MissingValue || qc == QCMissing
 }
 
 //goland:noinspection GoMixedReceiverTypes
@@ -215,6 +265,11 @@ func ReadData(r io.Reader) (Station, error) {
 
 	debugPrint("processed %d entries\n", len(station.Entries))
 
+	// files are written in time order, but don't let the lookups depend on it
+	if !station.Sorted() {
+		station.Sort()
+	}
+
 	return *station, errors.Join(errs...)
 }
 
@@ -261,7 +316,7 @@ func (d *Data) OmitInvalidOrMissing() {
 	timeType := reflect.TypeOf(time.Time{})
 	for i := 0; i < count; i++ {
 		field := reflect.ValueOf(d).Elem().Field(i)
-		if field.Type().Kind() == reflect.Float64 && field.Float() == -9999.9 {
+		if field.Type().Kind() == reflect.Float64 && field.Float() == MissingValue {
 			field.SetZero()
 		}
 		if field.Type().Kind() == reflect.Int && field.Int() == -9999 {
@@ -286,93 +341,18 @@ func ParseLine(fields []string) (Data, error) {
 		return *data, err
 	}
 
-	for i, field := range fields {
-		switch i {
-		case 7:
-			data.SolarZenithAngle = parseFloat(field)
-		case 8:
-			data.DownwellingSolar = parseFloat(field)
-		case 9:
-			// data.QCDWSolar, _ = strconv.Atoi(field)
-		case 10:
-			data.UpwellingSolar = parseFloat(field)
-		case 11:
-			// data.QCUWSolar, _ = strconv.Atoi(field)
-		case 12:
-			data.DirectNormalSolar = parseFloat(field)
-		case 13:
-			// data.QCDirectN, _ = strconv.Atoi(field)
-		case 14:
-			data.DownwellingDiffuseSolar = parseFloat(field)
-		case 15:
-			// data.QCDiffuse, _ = strconv.Atoi(field)
-		case 16:
-			data.DownwellingIR = parseFloat(field)
-		case 17:
-			// data.QCDWIR, _ = strconv.Atoi(field)
-		case 18:
-			data.DownwellingIRCaseTemp = parseFloat(field)
-		case 19:
-			// data.QCDWCasetemp, _ = strconv.Atoi(field)
-		case 20:
-			data.DownwellingIRDomeTemp = parseFloat(field)
-		case 21:
-			// data.QCDWDometemp, _ = strconv.Atoi(field)
-		case 22:
-			data.UpwellingIR = parseFloat(field)
-		case 23:
-			// data.QCUWIR, _ = strconv.Atoi(field)
-		case 24:
-			data.UpwellingIRCaseTemp = parseFloat(field)
-		case 25:
-			// data.QCUWCasetemp, _ = strconv.Atoi(field)
-		case 26:
-			data.UpwellingIRDomeTemp = parseFloat(field)
-		case 27:
-			// data.QCUWDometemp, _ = strconv.Atoi(field)
-		case 28:
-			data.GlobalUVB = parseFloat(field)
-		case 29:
-			// data.QCUVB, _ = strconv.Atoi(field)
-		case 30:
-			data.PhotosyntheticallyActiveRadiation = parseFloat(field)
-		case 31:
-			// data.QCPAR, _ = strconv.Atoi(field)
-		case 32:
-			data.NetSolar = parseFloat(field)
-		case 33:
-			// data.QCNetSolar, _ = strconv.Atoi(field)
-		case 34:
-			data.NetIR = parseFloat(field)
-		case 35:
-			// data.QCNetIR, _ = strconv.Atoi(field)
-		case 36:
-			data.TotalNetRadiation = parseFloat(field)
-		case 37:
-			// data.QCTotalNet, _ = strconv.Atoi(field)
-		case 38:
-			data.TemperatureC = parseFloat(field)
-		case 39:
-			// data.QCTemp, _ = strconv.Atoi(field)
-		case 40:
-			data.RelativeHumidity = parseFloat(field)
-		case 41:
-			// data.QCRH, _ = strconv.Atoi(field)
-		case 42:
-			data.WindSpeedMetersPerSecond = parseFloat(field)
-		case 43:
-			// data.QCWindSpd, _ = strconv.Atoi(field)
-		case 44:
-			data.WindDirectionDegrees = parseFloat(field)
-		case 45:
-			// data.QCWindDir, _ = strconv.Atoi(field)
-		case 46:
-			data.BarometricPressure = parseFloat(field)
-		case 47:
-			// data.QCPressure, _ = strconv.Atoi(field)
-		default:
-			//
+	for _, v := range Variables {
+		if v.Column >= len(fields) {
+			continue
+		}
+		value, qc := parseFloat(fields[v.Column]), QCGood
+		if v.QCColumn >= 0 && v.QCColumn < len(fields) {
+			qc = parseQC(fields[v.QCColumn])
+		}
+		if value == MissingValue && v.QC != nil {
+			qc = QCMissing
 		}
+		v.Set(data, value, qc)
 	}
 
 	if len(fields) < 47 {
@@ -384,6 +364,14 @@ func ParseLine(fields []string) (Data, error) {
 	return *data, err
 }
 
+func parseQC(s string) int {
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		return QCBad
+	}
+	return value
+}
+
 func parseFloat(s string) float64 {
 	value, err := strconv.ParseFloat(s, 64)
 	if err != nil {
@@ -67,7 +67,15 @@ func TestParseLine(t *testing.T) {
 				Timestamp:        time.Date(1995, time.January, 1, 0, 0, 0, 0, time.UTC),
 				SolarZenithAngle: 0.0,
 				DownwellingSolar: 0.0,
-				// QCDWSolar: 1,
+				QC: QCFlags{
+					DownwellingSolar: QCMissing, UpwellingSolar: QCMissing, DirectNormalSolar: QCMissing,
+					DownwellingDiffuseSolar: QCMissing, DownwellingIR: QCMissing, DownwellingIRCaseTemp: QCMissing,
+					DownwellingIRDomeTemp: QCMissing, UpwellingIR: QCMissing, UpwellingIRCaseTemp: QCMissing,
+					UpwellingIRDomeTemp: QCMissing, GlobalUVB: QCMissing, PhotosyntheticallyActiveRadiation: QCMissing,
+					NetSolar: QCMissing, NetIR: QCMissing, TotalNetRadiation: QCMissing, TemperatureC: QCMissing,
+					RelativeHumidity: QCMissing, WindSpeedMetersPerSecond: QCMissing, WindDirectionDegrees: QCMissing,
+					BarometricPressure: QCMissing,
+				},
 			},
 			wantErr: false,
 		},
@@ -88,6 +96,45 @@ func TestParseLine(t *testing.T) {
 	}
 }
 
+func TestIsMissing(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    float64
+		qc       int
+		expected bool
+	}{
+		{"good value", 12.5, QCGood, false},
+		{"good zero", 0, QCGood, false},
+		{"omitted missing", 0, QCMissing, true},
+		{"raw missing", MissingValue, QCBad, true},
+		{"bad but present", 12.5, QCBad, false},
+		{"bad zero", 0, QCBad, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsMissing(tc.value, tc.qc); got != tc.expected {
+				t.Errorf("IsMissing(%v, %d) == %t, expected %t", tc.value, tc.qc, got, tc.expected)
+			}
+		})
+	}
+}
+
+// readTestStation parses the day of Desert Rock data in testdata.
+func readTestStation(t *testing.T) Station {
+	t.Helper()
+	f, err := os.Open("testdata/dra24048.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	station, err := ReadData(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return station
+}
+
 func TestReadData(t *testing.T) {
 	f, err := os.OpenFile("testdata/dra24048.dat", os.O_RDONLY, 0644)
 	if err != nil {
@@ -0,0 +1,276 @@
+package surfrad
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Surface is a plane's orientation in degrees. Tilt is from horizontal, azimuth is the direction
+// the plane faces, clockwise from north (180 faces south).
+type Surface struct {
+	Tilt    float64 `json:"tilt"`
+	Azimuth float64 `json:"azimuth"`
+}
+
+type TranspositionModel int
+
+const (
+	Isotropic TranspositionModel = iota
+	HayDavies
+	Perez
+)
+
+func (m TranspositionModel) String() string {
+	switch m {
+	case Isotropic:
+		return "isotropic"
+	case HayDavies:
+		return "haydavies"
+	case Perez:
+		return "perez"
+	default:
+		return fmt.Sprintf("TranspositionModel(%d)", int(m))
+	}
+}
+
+// POA is plane-of-array irradiance in W m^-2. All components are MissingValue if an input was missing.
+type POA struct {
+	Timestamp        time.Time `json:"timestamp"`
+	Surface          Surface   `json:"surface"`
+	AngleOfIncidence float64   `json:"aoi"`
+	Beam             float64   `json:"beam"`
+	SkyDiffuse       float64   `json:"sky_diffuse"`
+	GroundReflected  float64   `json:"ground_reflected"`
+	Total            float64   `json:"total"`
+}
+
+func missingPOA(ts time.Time, surface Surface) POA {
+	return POA{
+		Timestamp:        ts,
+		Surface:          surface,
+		AngleOfIncidence: MissingValue,
+		Beam:             MissingValue,
+		SkyDiffuse:       MissingValue,
+		GroundReflected:  MissingValue,
+		Total:            MissingValue,
+	}
+}
+
+// cosAngleOfIncidence returns the cosine of the angle between the sun and the plane's normal.
+func cosAngleOfIncidence(surface Surface, zenith, azimuth float64) float64 {
+	zen, tilt := zenith*degToRad, surface.Tilt*degToRad
+	return math.Cos(zen)*math.Cos(tilt) + math.Sin(zen)*math.Sin(tilt)*math.Cos((azimuth-surface.Azimuth)*degToRad)
+}
+
+// AngleOfIncidence returns the angle in degrees between the sun and the plane's normal.
+func AngleOfIncidence(surface Surface, zenith, azimuth float64) float64 {
+	return math.Acos(math.Max(-1, math.Min(1, cosAngleOfIncidence(surface, zenith, azimuth)))) * radToDeg
+}
+
+// perez1990 holds the "allsitescomposite1990" F1 and F2 coefficients for the eight sky clearness bins.
+var perez1990 = [8][6]float64{
+	{-0.0080, 0.5880, -0.0620, -0.0600, 0.0720, -0.0220},
+	{0.1300, 0.6830, -0.1510, -0.0190, 0.0660, -0.0290},
+	{0.3300, 0.4870, -0.2210, 0.0550, -0.0640, -0.0260},
+	{0.5680, 0.1870, -0.2950, 0.1090, -0.1520, -0.0140},
+	{0.8730, -0.3920, -0.3620, 0.2260, -0.4620, 0.0010},
+	{1.1320, -1.2370, -0.4120, 0.2880, -0.8230, 0.0560},
+	{1.0600, -1.6000, -0.3590, 0.2640, -1.1270, 0.1310},
+	{0.6780, -0.3270, -0.2500, 0.1560, -1.3770, 0.2510},
+}
+
+var perezClearnessBins = [7]float64{1.065, 1.23, 1.5, 1.95, 2.8, 4.5, 6.2}
+
+func perezSkyDiffuse(surface Surface, zenith, cosAOI, dni, dhi, dniExtra float64) float64 {
+	if dhi <= 0 {
+		return 0
+	}
+
+	z := zenith * degToRad
+	const kappa = 1.041
+	eps := ((dhi+dni)/dhi + kappa*z*z*z) / (1 + kappa*z*z*z)
+
+	bin := len(perezClearnessBins)
+	for i, upper := range perezClearnessBins {
+		if eps < upper {
+			bin = i
+			break
+		}
+	}
+
+	am := RelativeAirmass(zenith)
+	if am == MissingValue {
+		return 0
+	}
+	delta := dhi * am / dniExtra
+
+	c := perez1990[bin]
+	f1 := math.Max(0, c[0]+c[1]*delta+c[2]*z)
+	f2 := c[3] + c[4]*delta + c[5]*z
+
+	tilt := surface.Tilt * degToRad
+	a := math.Max(0, cosAOI)
+	b := math.Max(math.Cos(85*degToRad), math.Cos(z))
+
+	return math.Max(0, dhi*((1-f1)*(1+math.Cos(tilt))/2+f1*a/b+f2*math.Sin(tilt)))
+}
+
+// Transpose computes plane-of-array irradiance from horizontal components. Angles are in degrees,
+// irradiances in W m^-2; dniExtra is the extraterrestrial normal irradiance used by the anisotropic models.
+func Transpose(model TranspositionModel, surface Surface, zenith, azimuth, ghi, dni, dhi, albedo, dniExtra float64) POA {
+	cosAOI := cosAngleOfIncidence(surface, zenith, azimuth)
+	tilt := surface.Tilt * degToRad
+
+	poa := POA{Surface: surface, AngleOfIncidence: math.Acos(math.Max(-1, math.Min(1, cosAOI))) * radToDeg}
+
+	if zenith < 90 {
+		poa.Beam = math.Max(0, dni*cosAOI)
+	}
+
+	isotropic := dhi * (1 + math.Cos(tilt)) / 2
+
+	switch model {
+	case HayDavies:
+		if zenith >= 90 || dniExtra <= 0 {
+			poa.SkyDiffuse = isotropic
+			break
+		}
+		ai := math.Max(0, dni/dniExtra)
+		rb := math.Max(0, cosAOI) / math.Max(math.Cos(zenith*degToRad), 0.01745)
+		poa.SkyDiffuse = math.Max(0, dhi*(ai*rb+(1-ai)*(1+math.Cos(tilt))/2))
+	case Perez:
+		if zenith >= 90 || dniExtra <= 0 {
+			poa.SkyDiffuse = isotropic
+			break
+		}
+		poa.SkyDiffuse = perezSkyDiffuse(surface, zenith, cosAOI, dni, dhi, dniExtra)
+	default:
+		poa.SkyDiffuse = isotropic
+	}
+
+	poa.GroundReflected = ghi * albedo * (1 - math.Cos(tilt)) / 2
+	poa.Total = poa.Beam + poa.SkyDiffuse + poa.GroundReflected
+
+	return poa
+}
+
+// Albedo returns the measured broadband surface albedo, UpwellingSolar / DownwellingSolar, or MissingValue
+// when either is missing or there's too little light for a meaningful ratio.
+func (d Data) Albedo() float64 {
+	const minIrradiance = 10
+	if IsMissing(d.DownwellingSolar, d.QC.DownwellingSolar) || IsMissing(d.UpwellingSolar, d.QC.UpwellingSolar) ||
+		d.DownwellingSolar < minIrradiance || d.UpwellingSolar < 0 {
+		return MissingValue
+	}
+	return math.Min(1, d.UpwellingSolar/d.DownwellingSolar)
+}
+
+// DefaultAlbedo is used for ground reflection when the record has no usable measured albedo.
+const DefaultAlbedo = 0.25
+
+// PlaneOfArray transposes the record's measured GHI, DNI and DHI onto surface, using the measured
+// albedo for ground reflection.
+func (d Data) PlaneOfArray(loc Location, surface Surface, model TranspositionModel) POA {
+	if IsMissing(d.DownwellingSolar, d.QC.DownwellingSolar) ||
+		IsMissing(d.DirectNormalSolar, d.QC.DirectNormalSolar) ||
+		IsMissing(d.DownwellingDiffuseSolar, d.QC.DownwellingDiffuseSolar) {
+		return missingPOA(d.Timestamp, surface)
+	}
+
+	pos := loc.SunPosition(d.Timestamp.Add(-30 * time.Second))
+	zen := d.Zenith(loc)
+
+	albedo := d.Albedo()
+	if albedo == MissingValue {
+		albedo = DefaultAlbedo
+	}
+
+	poa := Transpose(model, surface, zen, pos.Azimuth,
+		math.Max(0, d.DownwellingSolar), math.Max(0, d.DirectNormalSolar), math.Max(0, d.DownwellingDiffuseSolar),
+		albedo, ExtraterrestrialIrradiance(d.Timestamp, DefaultSolarConstant))
+	poa.Timestamp = d.Timestamp
+
+	return poa
+}
+
+// PlaneOfArray transposes every entry onto a fixed surface.
+func (s Station) PlaneOfArray(surface Surface, model TranspositionModel) []POA {
+	out := make([]POA, len(s.Entries))
+	for i, d := range s.Entries {
+		out[i] = d.PlaneOfArray(s.LocatedAt, surface, model)
+	}
+	return out
+}
+
+// SingleAxisTracker describes a single-axis tracker. Rotation is right-handed about the axis direction,
+// so for an axis pointing south positive angles face west, and zero is level with the axis.
+type SingleAxisTracker struct {
+	AxisTilt    float64 // degrees
+	AxisAzimuth float64 // direction the axis points, clockwise from north
+	MaxAngle    float64 // rotation limit in degrees, e.g. 60
+	Backtrack   bool
+	GCR         float64 // ground coverage ratio, collector width / row spacing, used for backtracking
+}
+
+// Rotation returns the tracker's rotation angle for a solar position, and the resulting surface.
+// Below the horizon the tracker is stowed level.
+func (t SingleAxisTracker) Rotation(zenith, azimuth float64) (float64, Surface) {
+	a, b := t.AxisAzimuth*degToRad, t.AxisTilt*degToRad
+
+	axis := [3]float64{math.Sin(a) * math.Cos(b), math.Cos(a) * math.Cos(b), math.Sin(b)}
+	normal := [3]float64{-math.Sin(a) * math.Sin(b), -math.Cos(a) * math.Sin(b), math.Cos(b)}
+	side := cross(axis, normal)
+
+	rotation := 0.0
+
+	if zenith < 90 {
+		zen, az := zenith*degToRad, azimuth*degToRad
+		sun := [3]float64{math.Sin(zen) * math.Sin(az), math.Sin(zen) * math.Cos(az), math.Cos(zen)}
+		rotation = math.Atan2(dot(sun, side), dot(sun, normal)) * radToDeg
+
+		if t.Backtrack && t.GCR > 0 {
+			// rows shade each other once cos(rotation) drops below the ground coverage ratio
+			if temp := math.Cos(rotation*degToRad) / t.GCR; temp < 1 {
+				correction := math.Acos(math.Max(-1, temp)) * radToDeg
+				rotation -= math.Copysign(correction, rotation)
+			}
+		}
+
+		if t.MaxAngle > 0 {
+			rotation = math.Max(-t.MaxAngle, math.Min(t.MaxAngle, rotation))
+		}
+	}
+
+	r := rotation * degToRad
+	var n [3]float64
+	for i := range n {
+		n[i] = math.Cos(r)*normal[i] + math.Sin(r)*side[i]
+	}
+
+	surface := Surface{
+		Tilt:    math.Acos(math.Max(-1, math.Min(1, n[2]))) * radToDeg,
+		Azimuth: mod360(math.Atan2(n[0], n[1]) * radToDeg),
+	}
+
+	return rotation, surface
+}
+
+func cross(u, v [3]float64) [3]float64 {
+	return [3]float64{u[1]*v[2] - u[2]*v[1], u[2]*v[0] - u[0]*v[2], u[0]*v[1] - u[1]*v[0]}
+}
+
+func dot(u, v [3]float64) float64 {
+	return u[0]*v[0] + u[1]*v[1] + u[2]*v[2]
+}
+
+// TrackerPlaneOfArray transposes every entry onto a single-axis tracker's moving surface.
+func (s Station) TrackerPlaneOfArray(tracker SingleAxisTracker, model TranspositionModel) []POA {
+	out := make([]POA, len(s.Entries))
+	for i, d := range s.Entries {
+		pos := s.LocatedAt.SunPosition(d.Timestamp.Add(-30 * time.Second))
+		_, surface := tracker.Rotation(pos.ApparentZenith, pos.Azimuth)
+		out[i] = d.PlaneOfArray(s.LocatedAt, surface, model)
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package surfrad
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTransposeHorizontal(t *testing.T) {
+	zen := 40.0
+	dni, dhi := 850.0, 150.0
+	ghi := dni*math.Cos(zen*degToRad) + dhi
+
+	for _, model := range []TranspositionModel{Isotropic, HayDavies, Perez} {
+		t.Run(model.String(), func(t *testing.T) {
+			poa := Transpose(model, Surface{Tilt: 0, Azimuth: 180}, zen, 150, ghi, dni, dhi, 0.2, 1361)
+			if math.Abs(poa.SkyDiffuse-dhi) > 1e-6 {
+				t.Errorf("sky diffuse on a horizontal plane == %v, expected DHI %v", poa.SkyDiffuse, dhi)
+			}
+			if poa.GroundReflected != 0 {
+				t.Errorf("a horizontal plane sees no ground, got %v", poa.GroundReflected)
+			}
+			if math.Abs(poa.Total-ghi) > 1e-6 {
+				t.Errorf("total on a horizontal plane == %v, expected GHI %v", poa.Total, ghi)
+			}
+		})
+	}
+}
+
+func TestTransposeTilted(t *testing.T) {
+	surface := Surface{Tilt: 30, Azimuth: 180}
+	zen, az := 40.0, 180.0
+	dni, dhi, ghi, albedo := 850.0, 150.0, 800.0, 0.2
+
+	iso := Transpose(Isotropic, surface, zen, az, ghi, dni, dhi, albedo, 1361)
+	hay := Transpose(HayDavies, surface, zen, az, ghi, dni, dhi, albedo, 1361)
+	perez := Transpose(Perez, surface, zen, az, ghi, dni, dhi, albedo, 1361)
+
+	if math.Abs(iso.AngleOfIncidence-10) > 1e-9 {
+		t.Errorf("AOI == %v, expected 10", iso.AngleOfIncidence)
+	}
+	if math.Abs(iso.Beam-dni*math.Cos(10*degToRad)) > 1e-9 {
+		t.Errorf("beam == %v", iso.Beam)
+	}
+	if math.Abs(iso.SkyDiffuse-dhi*(1+math.Cos(30*degToRad))/2) > 1e-9 {
+		t.Errorf("isotropic sky diffuse == %v", iso.SkyDiffuse)
+	}
+	if math.Abs(iso.GroundReflected-ghi*albedo*(1-math.Cos(30*degToRad))/2) > 1e-9 {
+		t.Errorf("ground reflected == %v", iso.GroundReflected)
+	}
+	// circumsolar brightening on a sun facing plane
+	if hay.SkyDiffuse <= iso.SkyDiffuse || perez.SkyDiffuse <= iso.SkyDiffuse {
+		t.Errorf("anisotropic models should exceed isotropic facing the sun: iso %v hay %v perez %v",
+			iso.SkyDiffuse, hay.SkyDiffuse, perez.SkyDiffuse)
+	}
+
+	// facing away from the sun there's no beam
+	away := Transpose(Perez, Surface{Tilt: 90, Azimuth: 0}, zen, az, ghi, dni, dhi, albedo, 1361)
+	if away.Beam != 0 || away.AngleOfIncidence <= 90 {
+		t.Errorf("north facing wall should get no beam: %+v", away)
+	}
+}
+
+func TestSingleAxisTracker(t *testing.T) {
+	tracker := SingleAxisTracker{AxisAzimuth: 180, MaxAngle: 90}
+
+	// with the sun in the plane of rotation the tracker points straight at it
+	rot, surface := tracker.Rotation(30, 270)
+	if math.Abs(rot-30) > 1e-9 || math.Abs(AngleOfIncidence(surface, 30, 270)) > 1e-6 {
+		t.Errorf("rotation %v, surface %+v", rot, surface)
+	}
+	if rot, _ = tracker.Rotation(30, 90); math.Abs(rot+30) > 1e-9 {
+		t.Errorf("morning rotation %v, expected -30", rot)
+	}
+
+	tracker.MaxAngle = 45
+	if rot, _ = tracker.Rotation(70, 270); rot != 45 {
+		t.Errorf("rotation %v should be limited to 45", rot)
+	}
+
+	tracker.Backtrack, tracker.GCR = true, 0.4
+	if rot, _ = tracker.Rotation(85, 265); rot <= 0 || rot > 15 {
+		t.Errorf("backtracking should flatten the tracker at low sun, got %v", rot)
+	}
+	if rot, _ = tracker.Rotation(20, 250); rot <= 0 || rot > 45 {
+		t.Errorf("no backtracking expected at high sun, got %v", rot)
+	}
+
+	if rot, surface = tracker.Rotation(100, 270); rot != 0 || surface.Tilt != 0 {
+		t.Errorf("tracker should stow level at night: %v %+v", rot, surface)
+	}
+}
+
+func TestStationPlaneOfArray(t *testing.T) {
+	station := readTestStation(t)
+
+	fixed := station.PlaneOfArray(Surface{Tilt: 35, Azimuth: 180}, Perez)
+	tracked := station.TrackerPlaneOfArray(SingleAxisTracker{AxisAzimuth: 180, MaxAngle: 60, Backtrack: true, GCR: 0.35}, HayDavies)
+
+	if len(fixed) != station.Len() || len(tracked) != station.Len() {
+		t.Fatal("expected one result per entry")
+	}
+
+	for i, d := range station.Entries {
+		missing := IsMissing(d.DownwellingSolar, d.QC.DownwellingSolar)
+		if missing != (fixed[i].Total == MissingValue) {
+			t.Errorf("%s: missing input and output disagree: %+v", d.Timestamp, fixed[i])
+		}
+		if !missing && (fixed[i].Total < 0 || tracked[i].Total < 0) {
+			t.Errorf("%s: negative plane of array irradiance", d.Timestamp)
+		}
+	}
+}
+
+func TestAlbedo(t *testing.T) {
+	cases := []struct {
+		name     string
+		d        Data
+		expected float64
+	}{
+		{"typical", Data{DownwellingSolar: 500, UpwellingSolar: 100}, 0.2},
+		{"too dark", Data{DownwellingSolar: 5, UpwellingSolar: 1}, MissingValue},
+		{"missing", Data{DownwellingSolar: 500, QC: QCFlags{UpwellingSolar: QCMissing}}, MissingValue},
+		{"clamped", Data{DownwellingSolar: 100, UpwellingSolar: 120}, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.d.Albedo(); math.Abs(got-tc.expected) > 1e-9 {
+				t.Errorf("Albedo() == %v, expected %v", got, tc.expected)
+			}
+		})
+	}
+}
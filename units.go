@@ -0,0 +1,215 @@
+package surfrad
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrUnknownConversion = errors.New("no conversion between units")
+
+// UnitConversion converts a value in From to To as value*Scale + Offset. Energy conversions turn an
+// irradiance into the irradiation over one record, so the value is first multiplied by the record
+// interval in seconds.
+type UnitConversion struct {
+	From, To string
+	Scale    float64
+	Offset   float64
+	Energy   bool
+	// Variables restricts the conversion to these variables, for conversions that depend on the quantity
+	// rather than just its unit, such as PAR to photon flux.
+	Variables []string
+}
+
+// Apply converts value, measured over step for energy conversions.
+func (c UnitConversion) Apply(value float64, step time.Duration) float64 {
+	if c.Energy {
+		value *= step.Seconds()
+	}
+	return value*c.Scale + c.Offset
+}
+
+func (c UnitConversion) appliesTo(v Variable) bool {
+	if c.From != v.Unit {
+		return false
+	}
+	if len(c.Variables) == 0 {
+		return true
+	}
+	for _, name := range c.Variables {
+		if name == v.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// UnitConversions are the conversions available from the units in Variables.
+var UnitConversions = []UnitConversion{
+	{From: "degC", To: "degF", Scale: 9.0 / 5, Offset: 32},
+	{From: "degC", To: "K", Scale: 1, Offset: 273.15},
+	{From: "K", To: "degC", Scale: 1, Offset: -273.15},
+	{From: "K", To: "degF", Scale: 9.0 / 5, Offset: 32 - 273.15*9/5},
+
+	{From: "hPa", To: "kPa", Scale: 0.1},
+	{From: "hPa", To: "Pa", Scale: 100},
+	{From: "hPa", To: "inHg", Scale: 1 / 33.8639},
+	{From: "hPa", To: "mmHg", Scale: 1 / 1.333224},
+	{From: "hPa", To: "atm", Scale: 1 / 1013.25},
+
+	{From: "m s-1", To: "km h-1", Scale: 3.6},
+	{From: "m s-1", To: "mph", Scale: 3600 / 1609.344},
+	{From: "m s-1", To: "knot", Scale: 3600 / 1852.0},
+
+	{From: "W m-2", To: "kW m-2", Scale: 1e-3},
+	{From: "W m-2", To: "J m-2", Scale: 1, Energy: true},
+	{From: "W m-2", To: "Wh m-2", Scale: 1 / 3600.0, Energy: true},
+	{From: "W m-2", To: "kWh m-2", Scale: 1 / 3.6e6, Energy: true},
+	{From: "W m-2", To: "MJ m-2", Scale: 1e-6, Energy: true},
+	// photosynthetic photon flux density, with the usual factor for daylight
+	{From: "W m-2", To: "umol m-2 s-1", Scale: 4.57, Variables: []string{"par"}},
+
+	{From: "mW m-2", To: "W m-2", Scale: 1e-3},
+	// SURFRAD's UVB is erythemally weighted, and one UV index unit is 25 mW m-2 of it
+	{From: "mW m-2", To: "UVI", Scale: 1 / 25.0, Variables: []string{"uvb"}},
+
+	{From: "%", To: "1", Scale: 0.01},
+
+	{From: "degree", To: "rad", Scale: degToRad},
+}
+
+// FindConversion returns the conversion of v's values to the unit to. Converting to v's own unit is the
+// identity.
+func FindConversion(v Variable, to string) (UnitConversion, error) {
+	if to == v.Unit {
+		return UnitConversion{From: to, To: to, Scale: 1}, nil
+	}
+	for _, c := range UnitConversions {
+		if c.To == to && c.appliesTo(v) {
+			return c, nil
+		}
+	}
+	return UnitConversion{}, fmt.Errorf("%w: %s from %s to %s", ErrUnknownConversion, v.Name, v.Unit, to)
+}
+
+// ConvertibleUnits lists the units v's values can be converted to, its own unit first.
+func ConvertibleUnits(v Variable) []string {
+	units := []string{v.Unit}
+	for _, c := range UnitConversions {
+		if c.appliesTo(v) {
+			units = append(units, c.To)
+		}
+	}
+	return units
+}
+
+// ConvertValue converts a single value of v to the unit to. Energy conversions assume a one minute
+// record, as in the SURFRAD files.
+func ConvertValue(v Variable, value float64, to string) (float64, error) {
+	c, err := FindConversion(v, to)
+	if err != nil {
+		return 0, err
+	}
+	return c.Apply(value, time.Minute), nil
+}
+
+// Units maps variable names to the unit to convert them to.
+type Units map[string]string
+
+// ParseUnits parses a comma separated list of name=unit pairs, such as "temp=degF,windspd=knot".
+func ParseUnits(s string) (Units, error) {
+	units := make(Units)
+	if strings.TrimSpace(s) == "" {
+		return units, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		name, unit, ok := strings.Cut(pair, "=")
+		name, unit = strings.TrimSpace(name), strings.TrimSpace(unit)
+		if !ok || name == "" || unit == "" {
+			return nil, fmt.Errorf("invalid unit %q, expected name=unit", pair)
+		}
+		units[name] = unit
+	}
+	return units, units.check()
+}
+
+func (u Units) check() error {
+	names := make([]string, 0, len(u))
+	for name := range u {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v, ok := LookupVariable(name)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownVariable, name)
+		}
+		if _, err := FindConversion(v, u[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Variables returns copies of vars with the unit, and the plausible range, of every converted variable
+// updated, for labelling converted data. Energy conversions assume one minute records.
+func (u Units) Variables(vars []Variable) []Variable {
+	out := make([]Variable, len(vars))
+	for i, v := range vars {
+		out[i] = v
+		to, ok := u[v.Name]
+		if !ok {
+			continue
+		}
+		c, err := FindConversion(v, to)
+		if err != nil {
+			continue
+		}
+		out[i].Unit = to
+		out[i].Min, out[i].Max = c.Apply(v.Min, time.Minute), c.Apply(v.Max, time.Minute)
+	}
+	return out
+}
+
+// ConvertUnits returns a copy of the station with the values of the named variables converted. Missing
+// values are left as they are. The Data fields keep their names, so the result is meant for export,
+// labelled by Units.Variables, rather than for further analysis or storage. Energy conversions use the
+// sampling interval as the record length.
+func (s Station) ConvertUnits(units Units) (Station, error) {
+	if err := units.check(); err != nil {
+		return Station{}, err
+	}
+
+	step := s.SamplingInterval()
+	type converter struct {
+		v Variable
+		c UnitConversion
+	}
+	var convs []converter
+	for _, v := range Variables {
+		if to, ok := units[v.Name]; ok && to != v.Unit {
+			c, _ := FindConversion(v, to)
+			convs = append(convs, converter{v, c})
+		}
+	}
+
+	out := s
+	out.Entries = make([]Data, len(s.Entries))
+	copy(out.Entries, s.Entries)
+	for i := range out.Entries {
+		d := &out.Entries[i]
+		for _, conv := range convs {
+			if conv.v.Missing(d) {
+				continue
+			}
+			qc := QCGood
+			if conv.v.QC != nil {
+				qc = conv.v.QC(d)
+			}
+			conv.v.Set(d, conv.c.Apply(conv.v.Value(d), step), qc)
+		}
+	}
+	return out, nil
+}
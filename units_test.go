@@ -0,0 +1,124 @@
+package surfrad
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConvertValue(t *testing.T) {
+	cases := []struct {
+		variable string
+		value    float64
+		to       string
+		expect   float64
+	}{
+		{"temp", 20, "degF", 68},
+		{"temp", -40, "degF", -40},
+		{"temp", 0, "K", 273.15},
+		{"dw_casetemp", 273.15, "degF", 32},
+		{"pressure", 1013.25, "kPa", 101.325},
+		{"pressure", 1013.25, "inHg", 29.9212},
+		{"pressure", 1013.25, "atm", 1},
+		{"windspd", 10, "knot", 19.4384},
+		{"windspd", 10, "mph", 22.3694},
+		{"windspd", 10, "km h-1", 36},
+		{"dw_solar", 600, "kWh m-2", 0.01},
+		{"dw_solar", 600, "Wh m-2", 10},
+		{"dw_solar", 1000, "kW m-2", 1},
+		{"par", 400, "umol m-2 s-1", 1828},
+		{"uvb", 200, "UVI", 8},
+		{"rh", 55, "1", 0.55},
+		{"winddir", 180, "rad", math.Pi},
+		{"temp", 12.5, "degC", 12.5},
+	}
+	for _, tc := range cases {
+		t.Run(tc.variable+" to "+tc.to, func(t *testing.T) {
+			v, _ := LookupVariable(tc.variable)
+			got, err := ConvertValue(v, tc.value, tc.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got-tc.expect) > 1e-4 {
+				t.Errorf("got %v, expected %v", got, tc.expect)
+			}
+		})
+	}
+
+	for _, bad := range [][2]string{{"temp", "kPa"}, {"dw_solar", "umol m-2 s-1"}, {"dw_solar", "UVI"}} {
+		v, _ := LookupVariable(bad[0])
+		if _, err := ConvertValue(v, 1, bad[1]); !errors.Is(err, ErrUnknownConversion) {
+			t.Errorf("%s to %s: expected ErrUnknownConversion, got %v", bad[0], bad[1], err)
+		}
+	}
+}
+
+func TestConvertibleUnits(t *testing.T) {
+	par, _ := LookupVariable("par")
+	units := ConvertibleUnits(par)
+	if units[0] != "W m-2" || units[len(units)-1] != "umol m-2 s-1" {
+		t.Errorf("unexpected units for par %v", units)
+	}
+	dw, _ := LookupVariable("dw_solar")
+	if got := ConvertibleUnits(dw); len(got) != len(units)-1 {
+		t.Errorf("PPFD should only apply to par, got %v", got)
+	}
+}
+
+func TestParseUnits(t *testing.T) {
+	units, err := ParseUnits(" temp=degF, windspd = knot")
+	if err != nil || len(units) != 2 || units["temp"] != "degF" || units["windspd"] != "knot" {
+		t.Errorf("ParseUnits == %v, %v", units, err)
+	}
+	if units, err = ParseUnits(""); err != nil || len(units) != 0 {
+		t.Errorf("expected no units, got %v, %v", units, err)
+	}
+	for _, bad := range []string{"temp", "temp=", "nope=degF", "temp=furlongs"} {
+		if _, err = ParseUnits(bad); err == nil {
+			t.Errorf("ParseUnits(%q) should fail", bad)
+		}
+	}
+}
+
+func TestStationConvertUnits(t *testing.T) {
+	st := readTestStation(t)
+	st.Entries[3].TemperatureC, st.Entries[3].QC.TemperatureC = 0, QCMissing
+
+	units := Units{"temp": "degF", "dw_solar": "Wh m-2"}
+	conv, err := st.ConvertUnits(units)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Entries[100].TemperatureC == conv.Entries[100].TemperatureC {
+		t.Fatal("the original station should be untouched")
+	}
+	for _, i := range []int{0, 100, 720} {
+		if want := st.Entries[i].TemperatureC*9/5 + 32; math.Abs(conv.Entries[i].TemperatureC-want) > 1e-9 {
+			t.Errorf("temperature %v, expected %v", conv.Entries[i].TemperatureC, want)
+		}
+		if want := st.Entries[i].DownwellingSolar / 60; math.Abs(conv.Entries[i].DownwellingSolar-want) > 1e-9 {
+			t.Errorf("dw_solar %v, expected %v", conv.Entries[i].DownwellingSolar, want)
+		}
+		if conv.Entries[i].RelativeHumidity != st.Entries[i].RelativeHumidity {
+			t.Error("unconverted variables should be untouched")
+		}
+	}
+	if d := conv.Entries[3]; d.TemperatureC != 0 || d.QC.TemperatureC != QCMissing {
+		t.Errorf("missing values should stay missing, got %v flagged %d", d.TemperatureC, d.QC.TemperatureC)
+	}
+
+	vars := units.Variables(Variables)
+	if vars[16].Unit != "degF" || vars[16].Min != -76 || vars[17].Unit != "%" || Variables[16].Unit != "degC" {
+		t.Errorf("unexpected labels %+v %+v", vars[16], vars[17])
+	}
+
+	if _, err = st.ConvertUnits(Units{"temp": "inHg"}); !errors.Is(err, ErrUnknownConversion) {
+		t.Errorf("expected ErrUnknownConversion, got %v", err)
+	}
+
+	c, _ := FindConversion(Variables[1], "kWh m-2")
+	if got := c.Apply(1000, time.Hour); math.Abs(got-1) > 1e-12 {
+		t.Errorf("an hour at 1000 W m-2 is %v kWh m-2", got)
+	}
+}
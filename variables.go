@@ -0,0 +1,224 @@
+package surfrad
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrUnknownVariable = errors.New("unknown variable")
+
+// Variable describes a quantity in a record, named by its SURFRAD column name, and how to get at it in Data.
+type Variable struct {
+	Name         string `json:"name"`      // SURFRAD short name, e.g. dw_solar
+	JSONName     string `json:"json_name"` // key in the JSON encoding of Data
+	Description  string `json:"description"`
+	Unit         string `json:"unit"`                    // UDUNITS style, e.g. W m-2
+	StandardName string `json:"standard_name,omitempty"` // CF standard name, empty if there is none
+	Column       int    `json:"column"`                  // zero based field index in a data line
+	QCColumn     int    `json:"qc_column"`               // field index of the QC flag, -1 for computed values
+
+	// Min and Max bound the physically plausible values. They're looser than SURFRAD's own QC limits
+	// and only catch values that can't be right anywhere.
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+
+	Value func(d *Data) float64 `json:"-"`
+	QC    func(d *Data) int     `json:"-"` // nil for computed values without a flag
+	// Set stores a value and its flag in d; the flag is ignored for computed values.
+	Set func(d *Data, value float64, qc int) `json:"-"`
+}
+
+// field completes v with accessors built from a function returning pointers to the value and its flag,
+// which is nil for computed values.
+func field(v Variable, f func(d *Data) (*float64, *int)) Variable {
+	v.Value = func(d *Data) float64 { p, _ := f(d); return *p }
+	v.Set = func(d *Data, value float64, qc int) {
+		p, q := f(d)
+		*p = value
+		if q != nil {
+			*q = qc
+		}
+	}
+	v.QCColumn = -1
+	if _, q := f(&Data{}); q != nil {
+		v.QC = func(d *Data) int { _, q := f(d); return *q }
+		v.QCColumn = v.Column + 1
+	}
+	return v
+}
+
+// Missing reports whether the variable is missing from d.
+func (v Variable) Missing(d *Data) bool {
+	qc := QCGood
+	if v.QC != nil {
+		qc = v.QC(d)
+	}
+	return IsMissing(v.Value(d), qc)
+}
+
+// Plausible reports whether value is within the variable's plausible range.
+func (v Variable) Plausible(value float64) bool {
+	return value >= v.Min && value <= v.Max
+}
+
+func (v Variable) String() string {
+	return v.Name
+}
+
+// Variables lists every variable in file column order.
+var Variables = []Variable{
+	field(Variable{Name: "zen", JSONName: "solar_zenith_angle", Description: "solar zenith angle",
+		Unit: "degree", StandardName: "solar_zenith_angle", Column: 7, Min: 0, Max: 180},
+		func(d *Data) (*float64, *int) { return &d.SolarZenithAngle, nil }),
+	field(Variable{Name: "dw_solar", JSONName: "downwelling_solar", Description: "downwelling global solar",
+		Unit: "W m-2", StandardName: "surface_downwelling_shortwave_flux_in_air", Column: 8, Min: -10, Max: 1500},
+		func(d *Data) (*float64, *int) { return &d.DownwellingSolar, &d.QC.DownwellingSolar }),
+	field(Variable{Name: "uw_solar", JSONName: "upwelling_solar", Description: "upwelling global solar",
+		Unit: "W m-2", StandardName: "surface_upwelling_shortwave_flux_in_air", Column: 10, Min: -10, Max: 1000},
+		func(d *Data) (*float64, *int) { return &d.UpwellingSolar, &d.QC.UpwellingSolar }),
+	field(Variable{Name: "direct_n", JSONName: "direct_normal_solar", Description: "direct-normal solar",
+		Unit: "W m-2", StandardName: "surface_direct_along_beam_downwelling_shortwave_flux_in_air", Column: 12, Min: -10, Max: 1400},
+		func(d *Data) (*float64, *int) { return &d.DirectNormalSolar, &d.QC.DirectNormalSolar }),
+	field(Variable{Name: "diffuse", JSONName: "downwelling_diffuse_solar", Description: "downwelling diffuse solar",
+		Unit: "W m-2", StandardName: "surface_diffuse_downwelling_shortwave_flux_in_air", Column: 14, Min: -10, Max: 1000},
+		func(d *Data) (*float64, *int) { return &d.DownwellingDiffuseSolar, &d.QC.DownwellingDiffuseSolar }),
+	field(Variable{Name: "dw_ir", JSONName: "downwelling_ir", Description: "downwelling thermal infrared",
+		Unit: "W m-2", StandardName: "surface_downwelling_longwave_flux_in_air", Column: 16, Min: 40, Max: 700},
+		func(d *Data) (*float64, *int) { return &d.DownwellingIR, &d.QC.DownwellingIR }),
+	field(Variable{Name: "dw_casetemp", JSONName: "downwelling_ir_case_temp", Description: "downwelling IR case temperature",
+		Unit: "K", Column: 18, Min: 200, Max: 340},
+		func(d *Data) (*float64, *int) { return &d.DownwellingIRCaseTemp, &d.QC.DownwellingIRCaseTemp }),
+	field(Variable{Name: "dw_dometemp", JSONName: "downwelling_ir_dome_temp", Description: "downwelling IR dome temperature",
+		Unit: "K", Column: 20, Min: 200, Max: 340},
+		func(d *Data) (*float64, *int) { return &d.DownwellingIRDomeTemp, &d.QC.DownwellingIRDomeTemp }),
+	field(Variable{Name: "uw_ir", JSONName: "upwelling_ir", Description: "upwelling thermal infrared",
+		Unit: "W m-2", StandardName: "surface_upwelling_longwave_flux_in_air", Column: 22, Min: 40, Max: 900},
+		func(d *Data) (*float64, *int) { return &d.UpwellingIR, &d.QC.UpwellingIR }),
+	field(Variable{Name: "uw_casetemp", JSONName: "upwelling_ir_case_temp", Description: "upwelling IR case temperature",
+		Unit: "K", Column: 24, Min: 200, Max: 340},
+		func(d *Data) (*float64, *int) { return &d.UpwellingIRCaseTemp, &d.QC.UpwellingIRCaseTemp }),
+	field(Variable{Name: "uw_dometemp", JSONName: "upwelling_ir_dome_temp", Description: "upwelling IR dome temperature",
+		Unit: "K", Column: 26, Min: 200, Max: 340},
+		func(d *Data) (*float64, *int) { return &d.UpwellingIRDomeTemp, &d.QC.UpwellingIRDomeTemp }),
+	field(Variable{Name: "uvb", JSONName: "global_uvb", Description: "global UVB",
+		Unit: "mW m-2", Column: 28, Min: -10, Max: 5000},
+		func(d *Data) (*float64, *int) { return &d.GlobalUVB, &d.QC.GlobalUVB }),
+	field(Variable{Name: "par", JSONName: "photosynthetically_active_radiation", Description: "photosynthetically active radiation",
+		Unit: "W m-2", StandardName: "surface_downwelling_photosynthetic_radiative_flux_in_air", Column: 30, Min: -10, Max: 800},
+		func(d *Data) (*float64, *int) {
+			return &d.PhotosyntheticallyActiveRadiation, &d.QC.PhotosyntheticallyActiveRadiation
+		}),
+	field(Variable{Name: "netsolar", JSONName: "net_solar", Description: "net solar (dw_solar - uw_solar)",
+		Unit: "W m-2", StandardName: "surface_net_downward_shortwave_flux", Column: 32, Min: -10, Max: 1500},
+		func(d *Data) (*float64, *int) { return &d.NetSolar, &d.QC.NetSolar }),
+	field(Variable{Name: "netir", JSONName: "net_ir", Description: "net infrared (dw_ir - uw_ir)",
+		Unit: "W m-2", StandardName: "surface_net_downward_longwave_flux", Column: 34, Min: -500, Max: 300},
+		func(d *Data) (*float64, *int) { return &d.NetIR, &d.QC.NetIR }),
+	field(Variable{Name: "totalnet", JSONName: "total_net", Description: "net radiation (netsolar + netir)",
+		Unit: "W m-2", StandardName: "surface_net_downward_radiative_flux", Column: 36, Min: -500, Max: 1500},
+		func(d *Data) (*float64, *int) { return &d.TotalNetRadiation, &d.QC.TotalNetRadiation }),
+	field(Variable{Name: "temp", JSONName: "temperature", Description: "10-meter air temperature",
+		Unit: "degC", StandardName: "air_temperature", Column: 38, Min: -60, Max: 60},
+		func(d *Data) (*float64, *int) { return &d.TemperatureC, &d.QC.TemperatureC }),
+	field(Variable{Name: "rh", JSONName: "relative_humidity", Description: "relative humidity",
+		Unit: "%", StandardName: "relative_humidity", Column: 40, Min: 0, Max: 100},
+		func(d *Data) (*float64, *int) { return &d.RelativeHumidity, &d.QC.RelativeHumidity }),
+	field(Variable{Name: "windspd", JSONName: "wind_speed", Description: "wind speed",
+		Unit: "m s-1", StandardName: "wind_speed", Column: 42, Min: 0, Max: 75},
+		func(d *Data) (*float64, *int) { return &d.WindSpeedMetersPerSecond, &d.QC.WindSpeedMetersPerSecond }),
+	field(Variable{Name: "winddir", JSONName: "wind_direction", Description: "wind direction, clockwise from north",
+		Unit: "degree", StandardName: "wind_from_direction", Column: 44, Min: 0, Max: 360},
+		func(d *Data) (*float64, *int) { return &d.WindDirectionDegrees, &d.QC.WindDirectionDegrees }),
+	field(Variable{Name: "pressure", JSONName: "barometric_pressure", Description: "station pressure",
+		Unit: "hPa", StandardName: "surface_air_pressure", Column: 46, Min: 500, Max: 1100},
+		func(d *Data) (*float64, *int) { return &d.BarometricPressure, &d.QC.BarometricPressure }),
+}
+
+// LookupVariable finds a variable by its SURFRAD column name.
+func LookupVariable(name string) (Variable, bool) {
+	for _, v := range Variables {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Variable{}, false
+}
+
+// SelectVariables looks up each name in turn. No names selects every variable.
+func SelectVariables(names []string) ([]Variable, error) {
+	if len(names) == 0 {
+		return Variables, nil
+	}
+	vars := make([]Variable, 0, len(names))
+	for _, name := range names {
+		v, ok := LookupVariable(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownVariable, name)
+		}
+		vars = append(vars, v)
+	}
+	return vars, nil
+}
+
+// MeasuredVariables returns the variables that carry a QC flag, leaving out those computed rather than
+// measured, such as the zenith angle.
+func MeasuredVariables() []Variable {
+	var vars []Variable
+	for _, v := range Variables {
+		if v.QC != nil {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// VariableNames returns the SURFRAD column names of every variable.
+func VariableNames() []string {
+	names := make([]string, len(Variables))
+	for i, v := range Variables {
+		names[i] = v.Name
+	}
+	return names
+}
+
+func variableIndex(name string) int {
+	for i, v := range Variables {
+		if v.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// RangeViolation is a value outside its variable's plausible range.
+type RangeViolation struct {
+	Timestamp time.Time `json:"timestamp"`
+	Variable  string    `json:"variable"`
+	Value     float64   `json:"value"`
+	QC        int       `json:"qc"`
+}
+
+// OutOfRange returns the values of vars, or of every variable if none are given, that are present but
+// implausible. Values SURFRAD already flagged are included, so the flags can be compared.
+func (s Station) OutOfRange(vars ...Variable) []RangeViolation {
+	if len(vars) == 0 {
+		vars = Variables
+	}
+	var out []RangeViolation
+	for i := range s.Entries {
+		d := &s.Entries[i]
+		for _, v := range vars {
+			value := v.Value(d)
+			if v.Missing(d) || v.Plausible(value) {
+				continue
+			}
+			qc := QCGood
+			if v.QC != nil {
+				qc = v.QC(d)
+			}
+			out = append(out, RangeViolation{Timestamp: d.Timestamp, Variable: v.Name, Value: value, QC: qc})
+		}
+	}
+	return out
+}
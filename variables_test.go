@@ -0,0 +1,145 @@
+package surfrad
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLookupVariable(t *testing.T) {
+	d := Data{TemperatureC: 12.5, RelativeHumidity: 0, QC: QCFlags{RelativeHumidity: QCMissing}}
+
+	cases := []struct {
+		name    string
+		found   bool
+		value   float64
+		missing bool
+	}{
+		{"temp", true, 12.5, false},
+		{"rh", true, 0, true},
+		{"zen", true, 0, false},
+		{"bogus", false, 0, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, ok := LookupVariable(tc.name)
+			if ok != tc.found {
+				t.Fatalf("LookupVariable(%q) found == %t", tc.name, ok)
+			}
+			if !ok {
+				return
+			}
+			if v.Value(&d) != tc.value || v.Missing(&d) != tc.missing {
+				t.Errorf("value %v missing %t", v.Value(&d), v.Missing(&d))
+			}
+		})
+	}
+
+	if len(Variables) != 21 {
+		t.Errorf("expected 21 variables, got %d", len(Variables))
+	}
+}
+
+func TestVariableRegistry(t *testing.T) {
+	raw, err := os.ReadFile("testdata/dra24048.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(strings.Split(string(raw), "\n")[2+720])
+	d, err := ParseLine(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, v := range Variables {
+		t.Run(v.Name, func(t *testing.T) {
+			if v.Description == "" || v.Unit == "" || v.Min >= v.Max {
+				t.Errorf("incomplete metadata %+v", v)
+			}
+			want := 7
+			if i > 0 {
+				want = 6 + 2*i
+			}
+			if v.Column != want {
+				t.Errorf("column %d, expected %d", v.Column, want)
+			}
+			if got, _ := strconv.ParseFloat(fields[v.Column], 64); got != v.Value(&d) {
+				t.Errorf("column %d holds %v, parsed %v", v.Column, got, v.Value(&d))
+			}
+			if v.QC != nil {
+				if got, _ := strconv.Atoi(fields[v.QCColumn]); got != v.QC(&d) {
+					t.Errorf("qc column %d holds %v, parsed %v", v.QCColumn, got, v.QC(&d))
+				}
+			} else if v.QCColumn != -1 {
+				t.Errorf("computed variable with qc column %d", v.QCColumn)
+			}
+
+			var probe Data
+			v.Set(&probe, 123.5, QCQuestionable)
+			encoded, err := json.Marshal(probe)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded map[string]any
+			if err = json.Unmarshal(encoded, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if decoded[v.JSONName] != 123.5 {
+				t.Errorf("JSON name %q doesn't match Data's encoding %s", v.JSONName, encoded)
+			}
+			if v.QC != nil && decoded["qc"].(map[string]any)[v.Name] != float64(QCQuestionable) {
+				t.Errorf("name %q doesn't match the QC flag's encoding %s", v.Name, encoded)
+			}
+		})
+	}
+}
+
+func TestSelectVariables(t *testing.T) {
+	vars, err := SelectVariables([]string{"temp", "dw_solar"})
+	if err != nil || len(vars) != 2 || vars[0].Name != "temp" || vars[1].Name != "dw_solar" {
+		t.Errorf("SelectVariables == %v, %v", vars, err)
+	}
+	if vars, err = SelectVariables(nil); err != nil || len(vars) != len(Variables) {
+		t.Errorf("expected every variable, got %d", len(vars))
+	}
+	if _, err = SelectVariables([]string{"temp", "nope"}); !errors.Is(err, ErrUnknownVariable) {
+		t.Errorf("expected ErrUnknownVariable, got %v", err)
+	}
+	if names := VariableNames(); len(names) != len(Variables) || names[1] != "dw_solar" {
+		t.Errorf("unexpected names %v", names)
+	}
+
+	measured := MeasuredVariables()
+	if len(measured) != len(Variables)-1 {
+		t.Errorf("expected every variable but the zenith angle, got %d", len(measured))
+	}
+	for _, v := range measured {
+		if v.Name == "zen" || v.QCColumn < 0 {
+			t.Errorf("%s is not measured", v.Name)
+		}
+	}
+}
+
+func TestOutOfRange(t *testing.T) {
+	st := readTestStation(t)
+	if got := st.OutOfRange(); len(got) != 0 {
+		t.Fatalf("expected the test data to be plausible, got %+v", got)
+	}
+
+	st.Entries[5].RelativeHumidity = 140
+	st.Entries[6].TemperatureC, st.Entries[6].QC.TemperatureC = -80, QCBad
+	st.Entries[7].TemperatureC, st.Entries[7].QC.TemperatureC = 0, QCMissing // missing, not implausible
+
+	got := st.OutOfRange()
+	if len(got) != 2 || got[0].Variable != "rh" || got[0].Value != 140 || got[1].Variable != "temp" || got[1].QC != QCBad {
+		t.Errorf("unexpected violations %+v", got)
+	}
+
+	rh, _ := LookupVariable("rh")
+	if got = st.OutOfRange(rh); len(got) != 1 || !got[0].Timestamp.Equal(st.Entries[5].Timestamp) {
+		t.Errorf("unexpected rh violations %+v", got)
+	}
+}